@@ -5,19 +5,19 @@
 package commands
 
 import (
-	"bufio"
 	"fmt"
+	"io"
 	"os"
 	"path"
 	"strings"
-	"time"
 
+	"github.com/toffanin/go-todo/library/v1"
 	"github.com/toffanin/go-todo/utils"
 
 	"github.com/codegangsta/cli"
 )
 
-func GetAdd() cli.Command {
+func GetAdd(s *Session) cli.Command {
 
 	return cli.Command{
 		Name:      "add",
@@ -29,6 +29,33 @@ func GetAdd() cli.Command {
 
    Project and content notation are optional. Quotation marks are optional too.
 
+   The task is validated before being added: lowercase priorities and dates
+   without leading zeros (ex.: due:2014-3-1) are normalized, while malformed
+   priorities and dates, and empty descriptions, are rejected. Projects and
+   contexts not used by any task yet are reported as warnings, along with the
+   nearest known one in case of typos; use --strict to reject them instead.
+
+   With the option '--under ITEM#' the task is added as a subtask of the task
+   ITEM#: the subtask references the stable identifier of its parent with the
+   add-on tag p: (ex.: p:3f2a9c01), and the parent gets a stable identifier if
+   it has none (see 'todo help ids').
+
+   The due (due:) and threshold (t:) dates can be written as expressions,
+   converted into YYYY-MM-DD dates when the task is added:
+
+      today, tomorrow      the obvious ones (and yesterday)
+      +3d, +2w, +1m, +1y   days, weeks, months and years from today
+      +5b                  business days (Monday to Friday) from today
+      friday, fri          the next Friday
+      next-monday          the Monday of the next week
+      next-week            the first day of the next week (and month, year)
+      mar-7, 7-march       the next 7th of March
+      eow, eom, eoy        the end of the week, the month or the year
+
+   The names of the days and the months are also understood in the language
+   set by TODOTXT_DATE_LOCALE (de, en, es, fr, it; the language of the system
+   by default), and the weeks start on TODOTXT_WEEK_START (ex.: monday).
+
 EXAMPLES
 
    Adds a simple task (quotes are optional):
@@ -52,8 +79,21 @@ EXAMPLES
 	  $ todo add "Buy food with amino acid taurine @petshop +BellyOfTheBeast"
 	  $ todo add "Buy huge amount of meat @butcher +BellyOfTheBeast"
 	  $ todo add "Hire a bouncer to protect @kitchen cupboard from the cat +BellyOfTheBeast"
+
+   Adds tasks with due and threshold dates:
+
+	  $ todo add "Send the report due:friday"
+	  $ todo add "Renew the passport t:next-monday due:eom"
+
+   Adds a subtask of the task on line 3:
+
+	  $ todo add --under 3 "Book the flights"
 `,
-		Action: func(c *cli.Context) {
+		Flags: []cli.Flag{
+			cli.BoolFlag{"strict", "rejects the tasks on validation warnings too"},
+			cli.StringFlag{"under", "", "adds the task as a subtask of ITEM#"},
+		},
+		Action: s.action(func(c *cli.Context) error {
 			// collect all the user-submitted arguments in an array
 			args := c.Args()
 
@@ -67,97 +107,154 @@ EXAMPLES
 			case len(args) == 0: // no options specified
 
 				// check incorrect usage of the command
-				if c.GlobalBool("f") {
-					fmt.Print("\nDetected missing option with command \"add [task]\"\n")
-					fmt.Print("Usage: todo -f add [task]\"\n\n")
+				if s.Config.Force {
 					cli.ShowCommandHelp(c, "add")
-					return
+					return utils.NewError(utils.ErrUsage, "Usage: todo -f add [task]",
+						"Detected missing option with command \"add [task]\"")
 				}
 
 				// invoke interactive input
-				task = utils.InteractiveInput("Add:")
+				var err error
+				if task, err = s.IO.Prompt("Add:"); err != nil {
+					return err
+				}
 
 			default: // collect all the arguments into a single string
 				task = strings.Join(args[0:], " ")
 			}
 
-			// TODO: validating input as a task
-
 			// sanitize input
 			task = utils.SanitizeInput(task)
 
-			// honour the -t global flag
-			if c.GlobalBool("t") {
-				date := time.Now().Format("2006-01-02 ")
-				task = date + task
-			}
-
 			// save the new task
-			addAction(task)
-		},
+			return s.addAction([]string{task}, c.Bool("strict"), c.String("under"))
+		}),
 	}
 }
 
-func GetAddm() cli.Command {
+func GetAddm(s *Session) cli.Command {
 
 	return cli.Command{
 		Name:      "addm",
 		ShortName: "",
 		Usage:     "Adds multiple tasks to your todo.txt file",
 		Description: `
-   This command can be used to add the specified tasks to your todo.txt file.
+   This command can be used to add the specified tasks to your todo.txt file,
+   one task per line. The tasks are taken from:
+
+      - the arguments, split on the newlines;
+      - the standard input when the only argument is '-';
+      - an interactive prompt without arguments, until an empty line or the
+        end of the input (Ctrl-D).
 
    Project and content notation are optional. Quotation marks are optional too.
+   The tasks are validated as with the command "add", and then added all at
+   once: if a task is rejected, none is added.
 
 EXAMPLES:
 
    Adds some simple tasks (quotes are optional):
 
-	  $ todo addm "Buy eggs and milk @grocery"
-	  $ > Buy a cake for Friday's dinner party with friends @backery
+	  $ todo addm "Buy eggs and milk @grocery
+	  Buy a cake for Friday's dinner party with friends @backery"
+
+   Adds the action items of a meeting:
+
+	  $ todo addm - < action-items.txt
+
+   Adds the tasks typed at the prompt:
+
+	  $ todo addm
+	  Add one task per line, then an empty line to finish:
+	  > Buy eggs and milk @grocery
+	  > Buy a cake for Friday's dinner party with friends @backery
+	  >
 `,
-		Action: func(c *cli.Context) {
+		Flags: []cli.Flag{
+			cli.BoolFlag{"strict", "rejects the tasks on validation warnings too"},
+			cli.StringFlag{"under", "", "adds the tasks as subtasks of ITEM#"},
+		},
+		Action: s.action(func(c *cli.Context) error {
 			// collect all the user-submitted arguments in an array
 			args := c.Args()
 
-			// check incorrect usage of the command
-			if len(args) == 0 {
-				fmt.Print("\nDetected missing option with command \"addm [task]\"\n")
-				fmt.Print("Usage: todo addm [task]\"\n\n")
-				cli.ShowCommandHelp(c, "addm")
-				return
+			// collect the tasks from the arguments, stdin or the prompt
+			var tasks []string
+			var err error
+			switch {
+			case len(args) == 1 && args[0] == "-":
+				tasks, err = s.readTasks()
+			case len(args) > 0:
+				tasks = strings.Split(strings.Join(args, " "), "\n")
+			default:
+				tasks, err = s.promptTasks()
+			}
+			if err != nil {
+				return err
 			}
 
-			// collect all the arguments into a single string
-			firstTask := strings.Join(args[0:], " ")
-
-			// invoke interactive input
-			secondTask := utils.InteractiveInput(">")
-
-			// TODO: validating input as a task
+			// sanitize tasks, skipping the empty lines
+			var valid []string
+			for _, task := range tasks {
+				if task = utils.SanitizeInput(task); task != "" {
+					valid = append(valid, task)
+				}
+			}
+			if len(valid) == 0 {
+				return utils.NewError(utils.ErrUsage, "Usage: todo addm [\"TASKS\" | -]", "No task to add.")
+			}
 
-			// sanitize tasks
-			firstTask = utils.SanitizeInput(firstTask)
-			secondTask = utils.SanitizeInput(secondTask)
+			// save tasks
+			return s.addAction(valid, c.Bool("strict"), c.String("under"))
+		}),
+	}
+}
 
-			// honour the -t global flag
-			if c.GlobalBool("t") {
-				date := time.Now().Format("2006-01-02 ")
-				firstTask = date + firstTask
-				secondTask = date + secondTask
-			}
+// Reads the tasks to add from the input, one per line, until its end.
+func (s *Session) readTasks() ([]string, error) {
+	var tasks []string
+	for {
+		line, err := s.IO.ReadLine()
+		if err == io.EOF {
+			return tasks, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, line)
+	}
+}
 
-			// save task
-			addAction(firstTask)
-			addAction(secondTask)
-		},
+// Prompts for the tasks to add, one per line, until an empty line or the end
+// of the input.
+func (s *Session) promptTasks() ([]string, error) {
+	if !s.IO.IsTerminal {
+		return nil, utils.NewError(utils.ErrUsage, "Use `todo addm -` to read the tasks from a file or a pipe.",
+			"Can't prompt the tasks to add: the input is not a terminal.")
+	}
+	fmt.Fprintln(s.IO.Out, "Add one task per line, then an empty line to finish:")
+
+	var tasks []string
+	for {
+		fmt.Fprint(s.IO.Out, "> ")
+		line, err := s.IO.ReadLine()
+		if err == io.EOF {
+			fmt.Fprintln(s.IO.Out)
+			return tasks, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if line = utils.SanitizeInput(line); line == "" {
+			return tasks, nil
+		}
+		tasks = append(tasks, line)
 	}
 }
 
-// Adds a task to a todo.txt file.
-func addAction(task string) {
+// Validates the directory which holds a todo.txt file.
+func checkTodoDir(todoFile string) error {
 
-	todoFile := utils.GetSetting("TODO_FILE")
 	todoDir := path.Dir(todoFile)
 	//fmt.Printf("*DIR: %s\n", todoDir)
 
@@ -169,66 +266,114 @@ func addAction(task string) {
 	if err != nil {
 		// path doesn't exists
 		if os.IsNotExist(err) {
-			fmt.Printf("DIR:%s doesn't exists.\n", todoDir)
-			fmt.Printf("Please create the missing directory with: `mkdir -p %s`.\n\n", todoDir)
-			os.Exit(1)
+			return utils.NewError(utils.ErrConfig,
+				fmt.Sprintf("Please create the missing directory with: `mkdir -p %s`.", todoDir),
+				"DIR:%s doesn't exists.", todoDir)
 		}
 
 		// brace yourself: unknown errors are coming
-		utils.Check(err)
+		return utils.WrapError(utils.ErrIO, err, "")
 	}
 
 	// path exists but is not a directory
 	if !finfo.IsDir() {
-		fmt.Printf("DIR:%s is not a directory.\n", todoDir)
-		fmt.Println("Please fix your todo.cfg file and be sure to specify a directory with an absolute path.")
-		os.Exit(1)
+		return utils.NewError(utils.ErrConfig,
+			"Please fix your todo.cfg file and be sure to specify a directory with an absolute path.",
+			"DIR:%s is not a directory.", todoDir)
 	}
+	return nil
+}
 
-	// determine the number of tasks in todo.txt
-	// TODO: with NewReadWriter the code should be more compact
-	//       buf := bufio.NewReadWriter(bufio.NewReader(r), bufio.NewWriter(w))
-	fd, err := os.OpenFile(todoFile, os.O_RDONLY|os.O_CREATE, 0600)
-	if err != nil {
-		// file isn't readable
-		if os.IsPermission(err) {
-			fmt.Printf("%s or %s don't have correct permission bits.\n", todoFile, todoDir)
-			fmt.Println("Please fix the directory / file permissions")
-			os.Exit(1)
+// Validates tasks and adds them to a todo.txt file, all at once: if a task is
+// rejected, none is added. The validation warnings are errors when strict is
+// true. If under isn't empty, the tasks are added as subtasks of the task it
+// references.
+func (s *Session) addAction(raws []string, strict bool, under string) error {
+
+	store := s.store("TODO_FILE")
+	if fs, ok := store.(*todotxt.FileStore); ok {
+		if err := checkTodoDir(fs.Path); err != nil {
+			return err
 		}
+	}
 
-		// brace yourself: unknown errors are coming
-		utils.Check(err)
+	// keep other processes off todo.txt until the tasks are numbered and added
+	if locker, ok := store.(todotxt.Locker); ok {
+		unlock, err := locker.Lock()
+		if err != nil {
+			return storeError(store, err)
+		}
+		defer unlock()
 	}
 
-	scanner := bufio.NewScanner(fd)
-	ntasks := 1
-	for scanner.Scan() {
-		ntasks++
+	// determine the number of tasks in todo.txt
+	tasks, err := loadTasks(store)
+	if err != nil {
+		return err
 	}
-	if err := scanner.Err(); err != nil {
-		fd.Close()
-		utils.Check(err)
+	ntasks := len(tasks)
+
+	// subtasks reference the stable identifier of their parent (p: tag)
+	var parent *todotxt.Task
+	assigned := false
+	if under != "" {
+		if parent, err = findTask(tasks, under); err != nil {
+			return err
+		}
+		if parent.StableId() == "" {
+			if err := tasks.AssignId(parent, s.idTag()); err != nil {
+				return err
+			}
+			assigned = true
+		}
 	}
-	//fmt.Printf("n. lines: %d\n", ntasks)
-	err = fd.Close()
-	utils.Check(err)
 
-	// Open todo.txt in append mode only
-	fd, err = os.OpenFile(todoFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
-	utils.Check(err)
-	defer fd.Close()
+	// normalize the tasks, honouring TODOTXT_DATE_ON_ADD (global flags -t / -T)
+	v, err := s.newTaskValidator(todotxt.NewIndex(tasks), strict)
+	if err != nil {
+		return err
+	}
+	added := todotxt.TaskList{}
+	for _, raw := range raws {
+		t, err := v.validate(raw)
+		if err != nil {
+			return err
+		}
 
-	// use buffered I/O
-	writer := bufio.NewWriter(fd)
+		// honour TODOTXT_AUTO_ID by tagging the task with a stable identifier
+		if s.Config.AutoId {
+			if err := tasks.AssignId(t, s.idTag()); err != nil {
+				return err
+			}
+		}
+		if parent != nil {
+			t.SetTag(todotxt.ParentTag, parent.StableId())
+		}
+		t.Id = uint64(len(tasks) + 1)
+		tasks = append(tasks, *t)
+		added = append(added, *t)
+		v.learn(t)
+	}
+	for _, warning := range v.warnings {
+		fmt.Fprintf(s.IO.Err, "TODO: Warning: %s\n", warning)
+	}
 
-	// add the task to todo.txt
-	_, err = writer.WriteString(task + "\n")
-	utils.Check(err)
-	err = writer.Flush()
-	utils.Check(err)
+	// add the tasks to todo.txt, rewriting it if the parent got an identifier
+	if assigned {
+		if err := saveTasks(store, tasks); err != nil {
+			return err
+		}
+	} else if err := store.Append(added...); err != nil {
+		return storeError(store, err)
+	}
 
 	// print summary
-	fmt.Printf("%d: %s\n", ntasks, task)
-	fmt.Printf("TODO: %d added\n", ntasks)
+	for _, t := range added {
+		fmt.Fprintf(s.IO.Out, "%d: %s\n", t.Id, &t)
+		fmt.Fprintf(s.IO.Out, "TODO: %d added\n", t.Id)
+	}
+	if len(added) > 1 {
+		fmt.Fprintf(s.IO.Out, "TODO: %d tasks added (%d-%d)\n", len(added), ntasks+1, len(tasks))
+	}
+	return nil
 }
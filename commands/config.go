@@ -0,0 +1,215 @@
+// Copyright (c) 2014, Mauro Toffanin. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+
+	"github.com/codegangsta/cli"
+
+	"github.com/toffanin/go-todo/utils"
+)
+
+// Returns the configuration file edited by the 'config' command: the file
+// given by the user, the file defining the setting name, the configuration
+// file with the highest precedence, or $HOME/todo.cfg. Profile files are
+// never edited, and settings defined by the section of a profile are edited
+// in the file holding the section.
+func (s *Session) configFile(file, name string) string {
+	if file != "" {
+		return file
+	}
+	if name != "" {
+		origin := s.Config.Origin(name)
+		if i := strings.Index(origin, " [profile "); i >= 0 {
+			origin = origin[:i]
+		}
+		for _, f := range s.Config.Files {
+			if f == origin {
+				return f
+			}
+		}
+	}
+	if len(s.Config.Files) > 0 {
+		return s.Config.Files[0]
+	}
+	return path.Join(s.Config.Home, "todo.cfg")
+}
+
+// Checks that name is a setting known to go-todo and that value is valid for
+// it. Values referring to variables can't be checked until they are loaded.
+func (s *Session) checkSetting(name, value string, force bool) error {
+	if !s.Config.Has(name) && !force {
+		return utils.NewError(utils.ErrUsage, "Use the global option -f to set it anyway.",
+			"Unknown setting %s.", name)
+	}
+	if strings.ContainsAny(value, "$~") {
+		return nil
+	}
+	return utils.WrapError(utils.ErrUsage, utils.NewConfig().Set(name, value), "")
+}
+
+// Reads, changes or removes the settings of a configuration file.
+func (s *Session) configAction(action string, args []string, file string, force bool) error {
+	switch action {
+	case "get":
+		if len(args) != 1 {
+			return configUsage()
+		}
+		if !s.Config.Has(args[0]) {
+			return utils.NewError(utils.ErrFailure, "", "Unknown setting %s.", args[0])
+		}
+		fmt.Fprintln(s.IO.Out, s.Config.Get(args[0]))
+
+	case "set":
+		if len(args) != 2 {
+			return configUsage()
+		}
+		if err := s.checkSetting(args[0], args[1], force); err != nil {
+			return err
+		}
+
+		cfg, err := utils.ReadConfigFile(s.configFile(file, args[0]))
+		if err != nil {
+			return utils.WrapError(utils.ErrIO, err, "")
+		}
+		cfg.Set(args[0], args[1])
+		if err := cfg.Save(); err != nil {
+			return utils.WrapError(utils.ErrIO, err, "")
+		}
+
+		if s.Config.Origin(args[0]) == utils.OriginEnvironment {
+			fmt.Fprintf(s.IO.Out, "TODO: %s is set in the environment, which takes precedence.\n", args[0])
+		}
+		fmt.Fprintf(s.IO.Out, "TODO: %s set in %s\n", args[0], cfg.Path)
+
+	case "unset":
+		if len(args) != 1 {
+			return configUsage()
+		}
+		cfg, err := utils.ReadConfigFile(s.configFile(file, args[0]))
+		if err != nil {
+			return utils.WrapError(utils.ErrIO, err, "")
+		}
+		if !cfg.Unset(args[0]) {
+			return utils.NewError(utils.ErrFailure, "", "%s isn't set in %s.", args[0], cfg.Path)
+		}
+		if err := cfg.Save(); err != nil {
+			return utils.WrapError(utils.ErrIO, err, "")
+		}
+		fmt.Fprintf(s.IO.Out, "TODO: %s removed from %s\n", args[0], cfg.Path)
+
+	case "edit":
+		if len(args) != 0 {
+			return configUsage()
+		}
+		editor := os.Getenv("VISUAL")
+		if editor == "" {
+			editor = os.Getenv("EDITOR")
+		}
+		if editor == "" {
+			editor = "vi"
+		}
+
+		// the editor may have arguments (ex.: "code --wait")
+		fields := strings.Fields(editor)
+		cmd := exec.Command(fields[0], append(fields[1:], s.configFile(file, ""))...)
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = s.IO.In, s.IO.Out, s.IO.Err
+		if err := cmd.Run(); err != nil {
+			return utils.NewError(utils.ErrFailure, "Please set $VISUAL or $EDITOR to your editor.",
+				"%s: %s", editor, err)
+		}
+
+	default:
+		return configUsage()
+	}
+	return nil
+}
+
+// Returns the error reporting an incorrect usage of the 'config' command.
+func configUsage() error {
+	return utils.NewError(utils.ErrUsage,
+		"Usage: todo config get KEY | set KEY VALUE | unset KEY | edit [--file PATH]",
+		"Detected wrong options with command \"config\"")
+}
+
+// Extracts the option --file from args, which can follow the subcommand.
+func fileOption(args []string, file string) ([]string, string) {
+	rest := []string{}
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case (arg == "--file" || arg == "-file") && i+1 < len(args):
+			file = args[i+1]
+			i++
+		case strings.HasPrefix(arg, "--file="):
+			file = strings.TrimPrefix(arg, "--file=")
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	return rest, file
+}
+
+func GetConfig(s *Session) cli.Command {
+
+	return cli.Command{
+		Name:  "config",
+		Usage: "Reads and edits the todo.cfg configuration files",
+		Description: `
+   This command reads and changes the settings of the configuration files
+   (see 'todo help env' for their locations and syntax):
+
+   get KEY          prints the value of the setting KEY
+   set KEY VALUE    sets KEY to VALUE in a configuration file
+   unset KEY        removes KEY from a configuration file
+   edit             opens a configuration file with $VISUAL or $EDITOR
+
+   By default 'set' and 'unset' change the file which defines KEY, or else the
+   configuration file with the highest precedence ($HOME/todo.cfg if there is
+   none); the option '--file' selects another file.
+
+   Only the assignments of KEY are changed: comments, the ordering of the
+   settings, their syntax ('export') and the other assignments sharing their
+   lines are preserved. New settings are appended at the end of the file.
+   VALUE is written inside double quotes when needed, so it can refer to other
+   variables (ex.: '$TODO_DIR/todo.txt').
+
+   Settings unknown to 'todo' are rejected unless the global option -f is set.
+
+USAGE:
+
+   $ todo config get KEY
+   $ todo config set KEY VALUE [--file PATH]
+   $ todo config unset KEY [--file PATH]
+   $ todo config edit [--file PATH]
+
+EXAMPLES:
+
+   Enables the stable identifiers in the project-local configuration file:
+
+      $ todo config set TODOTXT_AUTO_ID 1 --file ./todo.cfg
+
+   Moves the done.txt file next to todo.txt:
+
+      $ todo config set DONE_FILE '$TODO_DIR/done.txt'
+`,
+		Flags: []cli.Flag{
+			cli.StringFlag{"file", "", "selects the configuration file to change"},
+		},
+		Action: s.action(func(c *cli.Context) error {
+			// collect all the user-submitted arguments in an array
+			args, file := fileOption(c.Args(), c.String("file"))
+			if len(args) == 0 {
+				return configUsage()
+			}
+
+			return s.configAction(args[0], args[1:], file, s.Config.Force)
+		}),
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright (c) 2014, Mauro Toffanin. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/toffanin/go-todo/library/v1"
+	"github.com/toffanin/go-todo/utils"
+)
+
+// Returns the parser of the date expressions relative to now, honouring
+// TODOTXT_DATE_LOCALE (the language of the system by default) and
+// TODOTXT_WEEK_START (the first day of the week of the language by default).
+func (s *Session) dateParser(now time.Time) (*todotxt.DateParser, error) {
+	p := todotxt.NewDateParser(now)
+
+	if name := s.Config.DateLocale; name != "" {
+		locale, ok := todotxt.LookupDateLocale(name)
+		if !ok {
+			return nil, utils.NewError(utils.ErrConfig,
+				"Please set TODOTXT_DATE_LOCALE to one of: "+strings.Join(dateLocales(), ", ")+".",
+				"Unknown date locale %q.", name)
+		}
+		p.Locale = locale
+	} else {
+		// languages without a built-in locale fall back on English
+		for _, env := range []string{"LC_ALL", "LC_TIME", "LANG"} {
+			if name := os.Getenv(env); name != "" {
+				if locale, ok := todotxt.LookupDateLocale(name); ok {
+					p.Locale = locale
+				}
+				break
+			}
+		}
+	}
+	p.WeekStart = p.Locale.WeekStart
+
+	if name := strings.ToLower(s.Config.WeekStart); name != "" {
+		day, ok := weekday(p, name)
+		if !ok {
+			return nil, utils.NewError(utils.ErrConfig,
+				"Please set TODOTXT_WEEK_START to a day of the week (ex.: monday), or to its number (0 is sunday).",
+				"Invalid week start %q.", s.Config.WeekStart)
+		}
+		p.WeekStart = day
+	}
+	return p, nil
+}
+
+// Returns the day of the week named name, in the language of the parser or
+// in English, or numbered from 0 (Sunday) to 6.
+func weekday(p *todotxt.DateParser, name string) (time.Weekday, bool) {
+	if n, err := strconv.Atoi(name); err == nil {
+		return time.Weekday(n), n >= 0 && n <= 6
+	}
+	if day, ok := p.Locale.Weekday(name); ok {
+		return day, true
+	}
+	return todotxt.DateLocales["en"].Weekday(name)
+}
+
+// Returns the names of the built-in date locales in lexical order.
+func dateLocales() []string {
+	var names []string
+	for name := range todotxt.DateLocales {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,230 @@
+// Copyright (c) 2014, Mauro Toffanin. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/codegangsta/cli"
+
+	"github.com/toffanin/go-todo/library/v1"
+	"github.com/toffanin/go-todo/utils"
+)
+
+// Marks the given tasks as done, adding the next occurrence of the recurring
+// ones (rec: tag). The tasks with open subtasks are handled according to
+// TODOTXT_COMPLETE_PARENT.
+func (s *Session) doAction(refs []string) error {
+	cascade, err := s.cascadeCompletion()
+	if err != nil {
+		return err
+	}
+
+	store := s.store("TODO_FILE")
+
+	// keep other processes off todo.txt until the tasks are saved
+	if locker, ok := store.(todotxt.Locker); ok {
+		unlock, err := locker.Lock()
+		if err != nil {
+			return storeError(store, err)
+		}
+		defer unlock()
+	}
+
+	tasks, err := loadTasks(store)
+	if err != nil {
+		return err
+	}
+
+	// collect the tasks first: the list grows with the recurring tasks
+	selected := []*todotxt.Task{}
+	seen := map[*todotxt.Task]bool{}
+	for _, ref := range refs {
+		task, err := findTask(tasks, ref)
+		if err != nil {
+			return err
+		}
+		if !seen[task] {
+			seen[task] = true
+			selected = append(selected, task)
+		}
+	}
+
+	// open subtasks block the completion, or are completed too; the subtasks
+	// given along with their parent aren't open
+	given := selected
+	for _, task := range given {
+		open := []*todotxt.Task{}
+		for _, sub := range tasks.Descendants(task) {
+			if sub.Completed || seen[sub] {
+				continue
+			}
+			if cascade {
+				seen[sub] = true
+				selected = append(selected, sub)
+			}
+			open = append(open, sub)
+		}
+		if len(open) > 0 && !cascade && !task.Completed {
+			return utils.NewError(utils.ErrFailure,
+				"Complete the subtasks first, or set TODOTXT_COMPLETE_PARENT=cascade to complete them along with their parent.",
+				"Task %d has open subtasks: %s.", task.Id, taskNumbers(open))
+		}
+	}
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	done, recurring := []todotxt.Task{}, todotxt.TaskList{}
+	completed := []*todotxt.Task{}
+	for _, task := range selected {
+		if task.Completed {
+			fmt.Fprintf(s.IO.Out, "TODO: %d is already marked done.\n", task.Id)
+			continue
+		}
+
+		next, err := task.Recur(today)
+		if err != nil {
+			value, _ := task.Tag(todotxt.RecurTag)
+			return utils.NewError(utils.ErrParse, recurrenceHint, "Task %d: invalid recurrence rec:%s.", task.Id, value)
+		}
+		task.Complete(today)
+		done = append(done, *task)
+		completed = append(completed, task)
+
+		if next != nil {
+			// stable identifiers are unique: the occurrence gets its own one
+			next.RemoveTag(todotxt.IdTag)
+			next.RemoveTag(todotxt.UuidTag)
+			if s.Config.AutoId {
+				if err := tasks.AssignId(next, s.idTag()); err != nil {
+					return err
+				}
+			}
+			next.Id = uint64(len(tasks) + len(recurring) + 1)
+			recurring = append(recurring, *next)
+		}
+	}
+	if len(done) == 0 {
+		return nil
+	}
+
+	// the tasks waiting on the ones completed now may be ready
+	unblocked := []*todotxt.Task{}
+	for _, task := range completed {
+		for _, dependent := range tasks.Dependents(task) {
+			if !dependent.Completed && !seen[dependent] && !tasks.Blocked(dependent) {
+				seen[dependent] = true
+				unblocked = append(unblocked, dependent)
+			}
+		}
+	}
+
+	tasks = append(tasks, recurring...)
+	if err := saveTasks(store, tasks); err != nil {
+		return err
+	}
+
+	// print summary
+	for _, task := range done {
+		fmt.Fprintf(s.IO.Out, "%d %s\n", task.Id, &task)
+		fmt.Fprintf(s.IO.Out, "TODO: %d marked as done.\n", task.Id)
+	}
+	for _, task := range recurring {
+		fmt.Fprintf(s.IO.Out, "%d %s\n", task.Id, &task)
+		fmt.Fprintf(s.IO.Out, "TODO: %d added (recurring).\n", task.Id)
+	}
+	for _, task := range unblocked {
+		fmt.Fprintf(s.IO.Out, "TODO: %d is ready (dependencies done).\n", task.Id)
+	}
+	return nil
+}
+
+// Returns true if TODOTXT_COMPLETE_PARENT requires completing the open
+// subtasks along with their parent, false if they block the completion.
+func (s *Session) cascadeCompletion() (bool, error) {
+	switch s.Config.CompleteParent {
+	case "", "block":
+		return false, nil
+	case "cascade":
+		return true, nil
+	}
+	return false, utils.NewError(utils.ErrConfig, "Please set TODOTXT_COMPLETE_PARENT to block or cascade.",
+		"Invalid value %q of TODOTXT_COMPLETE_PARENT.", s.Config.CompleteParent)
+}
+
+// recurrenceHint explains the syntax of the rec: tag.
+const recurrenceHint = "Recurrences are written as rec:[+]N[dwmyb] (ex.: rec:1w, rec:+1m, rec:2b)."
+
+func GetDo(s *Session) cli.Command {
+
+	return cli.Command{
+		Name:  "do",
+		Usage: "Marks tasks as done",
+		Description: `
+   Marks the task on line ITEM# as done in todo.txt. Several tasks can be
+   given, separated by spaces or commas; stable identifiers (id:/uuid: tags)
+   can be used in place of the task numbers.
+
+   When a recurring task is done, its next occurrence is added to todo.txt.
+   The add-on tag rec: holds the interval between the occurrences, as a
+   number of days (d), weeks (w), months (m), years (y) or business days (b,
+   Monday to Friday):
+
+      rec:1w    the next occurrence is due a week after the completion
+      rec:+1m   the next occurrence is due a month after the previous due
+                date (strict recurrence)
+      rec:2b    the next occurrence is due two business days after the
+                completion
+
+   The threshold date (t:) of the next occurrence keeps its distance from the
+   due date; tasks without a due date get one.
+
+   A task with open subtasks (see 'todo help add') can't be marked as done,
+   unless TODOTXT_COMPLETE_PARENT is set to 'cascade': then its open subtasks
+   are marked as done too.
+
+   The tasks waiting on the completed ones (see 'todo help list') which have
+   no more open dependencies are reported as ready.
+
+USAGE:
+
+   $ todo do ITEM#[, ITEM#, ITEM#, ...]
+
+EXAMPLES:
+
+   Given this todo.txt as a reference:
+      Water the plants due:2014-06-02 rec:3d
+      Pay the rent t:2014-06-25 due:2014-07-01 rec:+1m
+
+   Marks both tasks as done on 2014-06-03:
+
+      $ todo do 1,2
+      1 x 2014-06-03 Water the plants due:2014-06-02 rec:3d
+      TODO: 1 marked as done.
+      2 x 2014-06-03 Pay the rent t:2014-06-25 due:2014-07-01 rec:+1m
+      TODO: 2 marked as done.
+      3 Water the plants due:2014-06-06 rec:3d
+      TODO: 3 added (recurring).
+      4 Pay the rent t:2014-07-26 due:2014-08-01 rec:+1m
+      TODO: 4 added (recurring).
+`,
+		Action: s.action(func(c *cli.Context) error {
+			// collect the task references, separated by spaces or commas
+			refs := strings.FieldsFunc(strings.Join(c.Args(), " "), func(r rune) bool {
+				return r == ' ' || r == ','
+			})
+
+			// check incorrect usage of the command
+			if len(refs) == 0 {
+				cli.ShowCommandHelp(c, "do")
+				return utils.NewError(utils.ErrUsage, "Usage: todo do ITEM#[, ITEM#, ITEM#, ...]",
+					"Detected missing option with command \"do ITEM#\"")
+			}
+			return s.doAction(refs)
+		}),
+	}
+}
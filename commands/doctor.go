@@ -0,0 +1,360 @@
+// Copyright (c) 2014, Mauro Toffanin. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/codegangsta/cli"
+
+	"github.com/toffanin/go-todo/library/v1"
+	"github.com/toffanin/go-todo/utils"
+)
+
+// maxLineLength is the longest line the todo.txt reader is able to scan.
+const maxLineLength = 64 * 1024
+
+var (
+	// lowercase or malformed priorities (ex.: "(a) task", "(AB) task")
+	badPriority = regexp.MustCompile(`^(x \S+ )?\(([a-z]|[A-Za-z]{2,})\) `)
+
+	// anything that looks like a date but isn't a valid one
+	looksLikeDate = regexp.MustCompile(`^\d{4}-\d{1,2}-\d{1,2}$`)
+)
+
+// A doctor collects and reports the problems of a todo.txt installation.
+type doctor struct {
+	out      io.Writer
+	problems int
+	warnings int
+}
+
+func (d *doctor) ok(format string, args ...interface{}) {
+	fmt.Fprintf(d.out, "  ok     %s\n", fmt.Sprintf(format, args...))
+}
+
+// warn reports an issue which doesn't prevent 'todo' from working.
+func (d *doctor) warn(fix string, format string, args ...interface{}) {
+	d.warnings++
+	fmt.Fprintf(d.out, "  warn   %s\n", fmt.Sprintf(format, args...))
+	if fix != "" {
+		fmt.Fprintf(d.out, "         fix: %s\n", fix)
+	}
+}
+
+// fail reports a problem which prevents 'todo' from working.
+func (d *doctor) fail(fix string, format string, args ...interface{}) {
+	d.problems++
+	fmt.Fprintf(d.out, "  error  %s\n", fmt.Sprintf(format, args...))
+	if fix != "" {
+		fmt.Fprintf(d.out, "         fix: %s\n", fix)
+	}
+}
+
+// Checks the configuration files and the selected profile.
+func (s *Session) checkConfig(d *doctor) {
+	if len(s.Config.Files) == 0 {
+		d.warn("create one with 'todo init' (see 'todo help env')", "no configuration file found")
+	}
+	for _, file := range s.Config.Files {
+		d.ok("configuration file %s", file)
+	}
+	if s.Config.Profile != "" {
+		d.ok("profile %s (%s)", s.Config.Profile, s.Config.Origin("TODOTXT_PROFILE"))
+	}
+	if s.Config.ProfileFile != "" {
+		d.ok("profile file %s", s.Config.ProfileFile)
+	}
+	if _, err := s.dateParser(time.Now()); err != nil {
+		d.fail("change it with 'todo config set' (see 'todo help add')", "%s", err)
+	}
+	if _, err := s.urgencyModel(nil, time.Now()); err != nil {
+		d.fail("change it with 'todo config set' (see 'todo help next')", "%s", err)
+	}
+	if _, err := s.escalationRules(); err != nil {
+		d.fail("change it with 'todo config set' (see 'todo help escalate')", "%s", err)
+	}
+}
+
+// Checks that the directory dir exists and is writable; name describes the
+// directory and setting is the setting to change to fix the path.
+func checkDir(d *doctor, name, setting, dir string) bool {
+	fix := fmt.Sprintf("create it with 'mkdir -p %s' or change %s with 'todo config set %s PATH'",
+		dir, setting, setting)
+
+	info, err := os.Stat(dir)
+	switch {
+	case os.IsNotExist(err):
+		d.fail(fix, "%s: %s doesn't exist", name, dir)
+		return false
+	case err != nil:
+		d.fail("", "%s: %s", name, err)
+		return false
+	case !info.IsDir():
+		d.fail(fix, "%s: %s is not a directory", name, dir)
+		return false
+	}
+
+	// files are replaced atomically, so the directory itself must be writable
+	tmp, err := ioutil.TempFile(dir, ".todo-doctor")
+	if err != nil {
+		d.fail(fmt.Sprintf("grant write permission with 'chmod u+w %s'", dir),
+			"%s: %s is not writable", name, dir)
+		return false
+	}
+	tmp.Close()
+	os.Remove(tmp.Name())
+
+	d.ok("%s %s", name, dir)
+	return true
+}
+
+// Checks that the file held by setting can be read and written. It returns
+// false if the file doesn't exist or can't be read.
+func checkFile(d *doctor, setting, file string) bool {
+	if file == "" {
+		d.fail(fmt.Sprintf("set it with 'todo config set %s FILE'", setting), "%s is not set", setting)
+		return false
+	}
+	if !checkDir(d, setting+" directory", setting, filepath.Dir(file)) {
+		return false
+	}
+
+	info, err := os.Stat(file)
+	switch {
+	case os.IsNotExist(err):
+		d.warn("", "%s: %s doesn't exist yet, it will be created", setting, file)
+		return false
+	case err != nil:
+		d.fail("", "%s: %s", setting, err)
+		return false
+	case !info.Mode().IsRegular():
+		d.fail(fmt.Sprintf("change %s with 'todo config set %s FILE'", setting, setting),
+			"%s: %s is not a regular file", setting, file)
+		return false
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		d.fail(fmt.Sprintf("grant read permission with 'chmod u+r %s'", file), "%s: %s", setting, err)
+		return false
+	}
+	f.Close()
+	f, err = os.OpenFile(file, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		d.fail(fmt.Sprintf("grant write permission with 'chmod u+w %s'", file), "%s: %s", setting, err)
+		return false
+	}
+	f.Close()
+
+	d.ok("%s %s", setting, file)
+	return true
+}
+
+// Checks the encoding of a todo.txt file and reports the tasks which can't
+// be parsed as intended.
+func checkTasks(d *doctor, setting, file string) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		d.fail("", "%s: %s", setting, err)
+		return
+	}
+
+	if bytes.HasPrefix(content, []byte("\xef\xbb\xbf")) {
+		d.warn("save the file as UTF-8 without BOM", "%s: %s starts with a byte order mark", setting, file)
+		content = content[3:]
+	}
+	if bytes.Contains(content, []byte("\r\n")) {
+		d.warn(fmt.Sprintf("convert the line endings with 'dos2unix %s'", file),
+			"%s: %s has Windows (CRLF) line endings", setting, file)
+	}
+
+	// tasks are numbered like the reader does, skipping the blank lines
+	problems, tasks := d.problems, 0
+	list := todotxt.TaskList{}
+	for i, line := range strings.Split(string(content), "\n") {
+		where := fmt.Sprintf("%s:%d", file, i+1)
+		if strings.TrimSpace(line) != "" {
+			tasks++
+		}
+		switch {
+		case len(line) > maxLineLength:
+			d.fail("split the line into several tasks", "%s: line longer than %d bytes", where, maxLineLength)
+			continue
+		case !utf8.ValidString(line):
+			d.fail("convert the file to UTF-8 (ex.: with iconv)", "%s: invalid UTF-8 text", where)
+			continue
+		case strings.ContainsRune(line, 0):
+			d.fail("remove the NUL characters from the line", "%s: the line contains NUL characters", where)
+			continue
+		}
+
+		raw := strings.TrimSpace(line)
+		if raw == "" {
+			continue
+		}
+		task, _ := todotxt.ParseTask(raw)
+		task.Id = uint64(tasks)
+		list = append(list, *task)
+
+		if badPriority.MatchString(raw) {
+			d.warn("priorities are a single uppercase letter, ex.: (A)",
+				"%s: malformed priority, read as text: %s", where, raw)
+		}
+
+		// dates which weren't recognized are left inside the text
+		words := strings.Fields(task.Todo)
+		if len(words) > 0 && looksLikeDate.MatchString(words[0]) {
+			d.warn("dates are written as YYYY-MM-DD", "%s: invalid date %s", where, words[0])
+		}
+		if due, ok := task.AdditionalTags["due"]; ok {
+			if _, err := time.Parse(todotxt.DateLayout, due); err != nil {
+				d.warn("dates are written as YYYY-MM-DD", "%s: invalid due date %s", where, due)
+			}
+		}
+	}
+	if cycle := list.DependencyCycle(); cycle != nil {
+		d.fail("remove one of the dep: tags of the cycle",
+			"%s: dependency cycle between tasks %s", setting, cyclePath(cycle))
+	}
+	if d.problems == problems {
+		d.ok("%s: %d tasks", setting, tasks)
+	}
+}
+
+// Checks the add-ons of the actions directory, which must be executable files
+// named after the action (ACTION or ACTION/ACTION).
+func (s *Session) checkActions(d *doctor) {
+	dir := s.Config.ActionsDir
+	if dir == "" {
+		return
+	}
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		d.ok("TODO_ACTIONS_DIR %s (no add-ons)", dir)
+		return
+	}
+	if !checkDir(d, "TODO_ACTIONS_DIR", "TODO_ACTIONS_DIR", dir) {
+		return
+	}
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		d.fail("", "TODO_ACTIONS_DIR: %s", err)
+		return
+	}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		action := filepath.Join(dir, entry.Name())
+		if entry.IsDir() {
+			action = filepath.Join(action, entry.Name())
+		}
+
+		info, err := os.Stat(action)
+		switch {
+		case err != nil:
+			d.warn(fmt.Sprintf("add the executable %s", action), "add-on %s: %s doesn't exist", entry.Name(), action)
+		case !info.Mode().IsRegular():
+			d.warn("", "add-on %s: %s is not a regular file", entry.Name(), action)
+		case info.Mode().Perm()&0111 == 0:
+			d.warn(fmt.Sprintf("make it executable with 'chmod +x %s'", action),
+				"add-on %s: %s is not executable", entry.Name(), action)
+		default:
+			d.ok("add-on %s", entry.Name())
+		}
+	}
+}
+
+// Checks that the external commands held by the settings can be found.
+func (s *Session) checkCommands(d *doctor) {
+	for _, setting := range []string{"TODOTXT_SORT_COMMAND", "TODOTXT_FINAL_FILTER"} {
+		command := strings.Fields(s.Config.Get(setting))
+		if len(command) == 0 {
+			continue
+		}
+		if _, err := exec.LookPath(command[0]); err != nil {
+			d.fail(fmt.Sprintf("install %s or change %s with 'todo config set %s COMMAND'",
+				command[0], setting, setting), "%s: command %s not found", setting, command[0])
+			continue
+		}
+		d.ok("%s %s", setting, s.Config.Get(setting))
+	}
+}
+
+// Checks the configuration and the task files, and reports the problems
+// found along with their fixes. It returns an error if there are problems.
+func (s *Session) doctorAction() error {
+	d := &doctor{out: s.IO.Out}
+
+	fmt.Fprintln(s.IO.Out, "Configuration:")
+	s.checkConfig(d)
+	checkDir(d, "TODO_DIR", "TODO_DIR", s.Config.TodoDir)
+	s.checkCommands(d)
+	if s.Config.Cache {
+		if _, err := os.Stat(s.cacheDir()); err == nil {
+			checkDir(d, "TODOTXT_CACHE_DIR", "TODOTXT_CACHE_DIR", s.cacheDir())
+		}
+	}
+
+	fmt.Fprintln(s.IO.Out, "Files:")
+	for _, setting := range []string{"TODO_FILE", "DONE_FILE"} {
+		if file := s.Config.Get(setting); checkFile(d, setting, file) {
+			checkTasks(d, setting, file)
+		}
+	}
+	checkFile(d, "REPORT_FILE", s.Config.ReportFile)
+
+	if s.Config.ActionsDir != "" {
+		fmt.Fprintln(s.IO.Out, "Add-ons:")
+		s.checkActions(d)
+	}
+
+	if d.problems > 0 {
+		return utils.NewError(utils.ErrFailure, "", "%d problems, %d warnings found", d.problems, d.warnings)
+	}
+	fmt.Fprintf(s.IO.Out, "TODO: %d problems, %d warnings found\n", d.problems, d.warnings)
+	return nil
+}
+
+func GetDoctor(s *Session) cli.Command {
+
+	return cli.Command{
+		Name:  "doctor",
+		Usage: "Checks the configuration and the todo.txt files for problems",
+		Description: `
+   This command verifies the installation of 'todo' and reports every problem
+   found along with a fix:
+
+   - the configuration files and the selected profile
+   - the directories and the files held by TODO_DIR, TODO_FILE, DONE_FILE and
+     REPORT_FILE, and their permissions
+   - the commands held by TODOTXT_SORT_COMMAND and TODOTXT_FINAL_FILTER
+   - the add-ons inside TODO_ACTIONS_DIR, which must be executable
+   - the encoding of todo.txt and done.txt (UTF-8, line endings) and the tasks
+     which can't be parsed as intended (ex.: malformed priorities or dates)
+
+   The command exits with a non-zero status if a problem is found; warnings
+   alone don't change the exit status.
+
+USAGE:
+
+   $ todo doctor
+`,
+		Action: s.action(func(c *cli.Context) error {
+			return s.doctorAction()
+		}),
+	}
+}
@@ -5,14 +5,75 @@
 package commands
 
 import (
+	"encoding/json"
 	"fmt"
-
-	"github.com/toffanin/go-todo/utils"
+	"strings"
 
 	"github.com/codegangsta/cli"
+
+	"github.com/toffanin/go-todo/utils"
 )
 
-func GetEnv() cli.Command {
+// envFormats formats a setting for each output format of the 'env' command.
+var envFormats = map[string]func(name, value string) string{
+	"sh": func(name, value string) string {
+		return "export " + name + "=" + shellQuote(value)
+	},
+	"fish": func(name, value string) string {
+		escaped := strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value)
+		return "set -gx " + name + " '" + escaped + "'"
+	},
+	"dotenv": func(name, value string) string {
+		escaped := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`, "\n", `\n`).Replace(value)
+		return name + `="` + escaped + `"`
+	},
+}
+
+// shellQuote quotes a value for a POSIX shell: values are enclosed in single
+// quotes, and every single quote is closed, escaped and reopened.
+func shellQuote(value string) string {
+	return "'" + strings.Replace(value, "'", `'\''`, -1) + "'"
+}
+
+// Prints the settings names in the given format, optionally along with
+// their origins.
+func (s *Session) envAction(names []string, format string, origin bool) error {
+	if format == "json" {
+		settings := map[string]interface{}{}
+		for _, name := range names {
+			settings[name] = s.Config.Get(name)
+			if origin {
+				settings[name] = map[string]string{
+					"value":  s.Config.Get(name),
+					"origin": s.Config.Origin(name),
+				}
+			}
+		}
+		// the keys of JSON objects are sorted by encoding/json
+		output, err := json.MarshalIndent(settings, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(s.IO.Out, string(output))
+		return nil
+	}
+
+	formatter, ok := envFormats[format]
+	if !ok {
+		return utils.NewError(utils.ErrUsage, "The formats are sh, fish, json and dotenv.",
+			"Unknown format %s.", format)
+	}
+	for _, name := range names {
+		line := formatter(name, s.Config.Get(name))
+		if origin {
+			line += " # " + s.Config.Origin(name)
+		}
+		fmt.Fprintln(s.IO.Out, line)
+	}
+	return nil
+}
+
+func GetEnv(s *Session) cli.Command {
 
 	return cli.Command{
 		Name:  "env",
@@ -21,18 +82,42 @@ func GetEnv() cli.Command {
    By default 'env' displays information as a shell script.
 
    The environment info will be dumped in a straight-forward form suitable for
-   sourcing into a shell script.
+   sourcing into a shell script (ex.: eval "$(todo env)").
 
    If one or more variable names is given as arguments, 'env' displays the value
-   of each named variable on its own line.
+   of each named variable on its own line; otherwise all the variables are
+   displayed in lexical order.
+
+   The option '--format' selects the output format:
+
+   sh       export NAME='value' (default)
+   fish     set -gx NAME 'value'
+   json     a JSON object mapping names to values
+   dotenv   NAME="value", as read by dotenv libraries
 
    The 'env' environment can be controlled through todo.cfg files (see section
    CONFIGURATION FILES) and environment variables.
 
 CONFIGURATION FILES:
 
-   Command line argument defaults can be set globally in a ~/.todo.cfg file or
-   set individual in a .todo.cfg for a specific directory.
+   Command line argument defaults can be set globally in a configuration file
+   or set individually in a todo.cfg for a specific project. The following
+   files are looked up, in order of precedence:
+
+   ./todo.cfg (or in the nearest parent directory, the way git finds .git)
+   $HOME/todo.cfg
+   $HOME/.todo.cfg
+   $HOME/.todo/config
+   $XDG_CONFIG_HOME/todo/config (default $HOME/.config/todo/config)
+   /etc/todo/config
+
+   Every configuration file can also define named profiles, selected with the
+   global option '--profile' or TODOTXT_PROFILE (see 'todo help profile').
+
+   If the option '--origin' is set then 'env' reports, next to each setting,
+   the configuration file which defined it ('environment' for environment
+   variables, 'default' for settings never defined). With '--format json' every
+   setting is reported as an object holding its value and its origin.
 
    Configuration files are simple text files with the following syntax:
 
@@ -46,8 +131,22 @@ CONFIGURATION FILES:
 
    # This is just an example
    export TODO_DIR="$HOME/todo"
+
+   Configuration files are evaluated in order, the way bash would source them:
+   values can refer to any variable previously defined or set in the
+   environment, and support quoting, escaping, ~ expansion and defaults:
+
+   # This is just an example
+   TEAM_DIR=~/team
+   export TODO_DIR="${XDG_DATA_HOME:-$HOME/.local/share}/todo"
+   export DONE_FILE="${TEAM_DIR}/done.txt"
+   : ${TODOTXT_ID_TAG:=uuid}
 `,
-		Action: func(c *cli.Context) {
+		Flags: []cli.Flag{
+			cli.BoolFlag{"origin", "reports where each setting has been defined"},
+			cli.StringFlag{"format", "sh", "selects the output format (sh, fish, json, dotenv)"},
+		},
+		Action: s.action(func(c *cli.Context) error {
 			// collect all the user-submitted arguments in an array
 			args := c.Args()
 
@@ -55,18 +154,13 @@ CONFIGURATION FILES:
 			/*fmt.Printf("pwd: %s\n", pwd)
 			fmt.Printf("HOME=\"%s\"\n", ENV["HOME"])*/
 
-			switch args.Present() {
-			case true:
-				// print only the required environment variables
-				for _, arg := range args {
-					fmt.Printf("%s=\"%s\"\n", arg, utils.GetSetting(arg))
-				}
-			case false:
-				// print all the environment variables
-				for k, v := range utils.GetSettings() {
-					fmt.Printf("%s=\"%s\"\n", k, v)
-				}
+			// print only the required environment variables, or all of them
+			names := []string(args)
+			if !args.Present() {
+				names = s.Config.Names()
 			}
-		},
+
+			return s.envAction(names, c.String("format"), c.Bool("origin"))
+		}),
 	}
 }
@@ -0,0 +1,149 @@
+// Copyright (c) 2014, Mauro Toffanin. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/codegangsta/cli"
+
+	"github.com/toffanin/go-todo/library/v1"
+	"github.com/toffanin/go-todo/utils"
+)
+
+// Returns the escalation rules of TODOTXT_ESCALATE.
+func (s *Session) escalationRules() ([]todotxt.EscalationRule, error) {
+	rules, err := todotxt.ParseEscalationRules(s.Config.Escalate)
+	if err != nil {
+		return nil, utils.NewError(utils.ErrConfig,
+			"Please set TODOTXT_ESCALATE to a list of WHEN:PRIORITY (ex.: 2d:B overdue:A), where WHEN is overdue, today or Nd.",
+			"Invalid escalation rules %q.", s.Config.Escalate)
+	}
+	return rules, nil
+}
+
+// Raises the priority of the tasks of todo.txt due soon according to the
+// rules, logging every change to w, and saves the tasks unless dryRun.
+// Returns the tasks along with the number of tasks escalated.
+func (s *Session) escalateTasks(rules []todotxt.EscalationRule, w io.Writer, dryRun bool) (todotxt.TaskList, int, error) {
+	store := s.store("TODO_FILE")
+
+	// keep other processes off todo.txt until the tasks are saved
+	if locker, ok := store.(todotxt.Locker); ok && !dryRun {
+		unlock, err := locker.Lock()
+		if err != nil {
+			return nil, 0, storeError(store, err)
+		}
+		defer unlock()
+	}
+
+	tasks, err := loadTasks(store)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	now := time.Now()
+	padding := len(strconv.Itoa(len(tasks)))
+	escalated := 0
+	for i := range tasks {
+		task := &tasks[i]
+		old, ok := task.Escalate(rules, now)
+		if !ok {
+			continue
+		}
+		escalated++
+		num := strconv.FormatUint(task.Id, 10)
+		fmt.Fprintf(w, "%s %s\n", utils.PaddingLeft(num, "0", padding), task)
+		if old == "" {
+			fmt.Fprintf(w, "TODO: %d prioritized (%s).\n", task.Id, task.Priority)
+		} else {
+			fmt.Fprintf(w, "TODO: %d re-prioritized from (%s) to (%s).\n", task.Id, old, task.Priority)
+		}
+	}
+
+	if escalated > 0 && !dryRun {
+		if err := saveTasks(store, tasks); err != nil {
+			return nil, 0, err
+		}
+	}
+	return tasks, escalated, nil
+}
+
+// Escalates the priorities of the tasks according to TODOTXT_ESCALATE.
+func (s *Session) escalateAction(dryRun bool) error {
+	rules, err := s.escalationRules()
+	if err != nil {
+		return err
+	}
+	if len(rules) == 0 {
+		return utils.NewError(utils.ErrConfig,
+			"Set TODOTXT_ESCALATE in todo.cfg (ex.: 2d:B overdue:A, see 'todo help escalate').",
+			"No escalation rules.")
+	}
+
+	_, escalated, err := s.escalateTasks(rules, s.IO.Out, dryRun)
+	if err != nil {
+		return err
+	}
+	switch {
+	case escalated == 0:
+		fmt.Fprintln(s.IO.Out, "TODO: No tasks to escalate.")
+	case dryRun:
+		fmt.Fprintf(s.IO.Out, "TODO: Dry run, %d tasks not saved.\n", escalated)
+	}
+	return nil
+}
+
+func GetEscalate(s *Session) cli.Command {
+
+	return cli.Command{
+		Name:  "escalate",
+		Usage: "Raises the priority of the tasks due soon",
+		Description: `
+   This command raises the priority of the open tasks whose due date (due:)
+   is near, according to the rules of TODOTXT_ESCALATE, and rewrites the
+   todo.txt file. Every task whose priority changes is printed, along with
+   its previous priority.
+
+   TODOTXT_ESCALATE is a list of WHEN:PRIORITY rules, separated by spaces or
+   commas, where WHEN is:
+
+      overdue    the tasks due before today
+      today      the tasks due today, or overdue
+      Nd         the tasks due within N days, or overdue
+
+   A task matching a rule gets at least the priority of the rule, and the
+   highest one if it matches several rules. Priorities are never lowered.
+
+   If TODOTXT_AUTO_ESCALATE is set, the priorities are escalated every time
+   the tasks are listed (see 'todo help list'), and the changes are printed
+   on the standard error.
+
+EXAMPLES:
+
+   Raises the tasks due within 2 days to (B), and the overdue tasks to (A),
+   in todo.cfg:
+
+      export TODOTXT_ESCALATE="2d:B overdue:A"
+
+   Escalates the priorities:
+
+      $ todo escalate
+      > 3 (A) Pay the rent due:2014-06-01
+      > TODO: 3 re-prioritized from (C) to (A).
+      > 7 (B) Send the report due:2014-06-03 +work
+      > TODO: 7 prioritized (B).
+`,
+		Flags: []cli.Flag{
+			cli.BoolFlag{"dry-run, n", "prints the changes without saving them"},
+		},
+		Action: s.action(func(c *cli.Context) error {
+			return s.escalateAction(c.Bool("dry-run"))
+		}),
+	}
+}
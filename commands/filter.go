@@ -0,0 +1,129 @@
+// Copyright (c) 2014, Mauro Toffanin. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/toffanin/go-todo/library/v1"
+)
+
+// taskFilter selects the tasks whose text matches a list of TERMs.
+//
+// The filter is a disjunction (OR) of conjunctions (AND) of terms; each term
+// can be negated. Terms are matched case-insensitively against the whole
+// task text.
+type taskFilter struct {
+	terms []string       // user-submitted TERMs, as they were given
+	any   [][]filterTerm // OR groups of AND terms
+}
+
+// filterTerm is a single TERM of a filter.
+type filterTerm struct {
+	text   string
+	negate bool
+}
+
+// Builds a filter from the user-submitted TERMs (see 'todo help list').
+func newTaskFilter(terms []string) *taskFilter {
+	f := &taskFilter{terms: terms}
+
+	group := []filterTerm{}
+	negate := false
+	for _, arg := range terms {
+		// a quoted logical statement holds several terms
+		for _, word := range strings.Fields(arg) {
+			word = strings.TrimSuffix(word, ",")
+			switch strings.ToLower(word) {
+			case "", "and", "&&":
+				continue
+			case "or", "|", "||":
+				if len(group) > 0 {
+					f.any = append(f.any, group)
+				}
+				group = []filterTerm{}
+				continue
+			case "-not", "not", "!":
+				negate = !negate
+				continue
+			}
+			if len(word) > 1 && (word[0] == '-' || word[0] == '!') {
+				word = word[1:]
+				negate = !negate
+			}
+			group = append(group, filterTerm{strings.ToLower(word), negate})
+			negate = false
+		}
+	}
+	if len(group) > 0 {
+		f.any = append(f.any, group)
+	}
+	return f
+}
+
+// Returns true if the filter has no terms, i.e. it matches every task.
+func (f *taskFilter) empty() bool {
+	return len(f.any) == 0
+}
+
+// Returns true if the task matches the filter.
+func (f *taskFilter) match(task *todotxt.Task) bool {
+	if f.empty() {
+		return true
+	}
+	text := strings.ToLower(task.String())
+	for _, group := range f.any {
+		matched := true
+		for _, term := range group {
+			if strings.Contains(text, term.text) == term.negate {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the tasks matching the filter.
+func (f *taskFilter) apply(tasks todotxt.TaskList) todotxt.TaskList {
+	selected := todotxt.TaskList{}
+	for i := range tasks {
+		if f.match(&tasks[i]) {
+			selected = append(selected, tasks[i])
+		}
+	}
+	return selected
+}
+
+// Returns the filter as it was given by the user.
+func (f *taskFilter) String() string {
+	return strings.Join(f.terms, " ")
+}
+
+// tasksByPriority sorts tasks alphabetically (case-insensitive), by priority
+// first if priority is set.
+type tasksByPriority struct {
+	tasks    todotxt.TaskList
+	priority bool
+}
+
+func (t tasksByPriority) Len() int      { return len(t.tasks) }
+func (t tasksByPriority) Swap(i, j int) { t.tasks[i], t.tasks[j] = t.tasks[j], t.tasks[i] }
+func (t tasksByPriority) Less(i, j int) bool {
+	if pi, pj := t.tasks[i].Priority, t.tasks[j].Priority; t.priority && pi != pj {
+		// tasks without priority come last
+		return pj == "" || pi != "" && pi < pj
+	}
+	return strings.ToLower(t.tasks[i].String()) < strings.ToLower(t.tasks[j].String())
+}
+
+// Sorts tasks by priority when a filter is given, alphabetically otherwise.
+func sortTasks(tasks todotxt.TaskList, f *taskFilter) {
+	sort.Stable(tasksByPriority{tasks, !f.empty()})
+}
@@ -0,0 +1,247 @@
+// Copyright (c) 2014, Mauro Toffanin. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/codegangsta/cli"
+
+	"github.com/toffanin/go-todo/library/v1"
+	"github.com/toffanin/go-todo/utils"
+)
+
+// taskGraph holds the tasks to render as a graph, grouped by project.
+type taskGraph struct {
+	groups   []string                   // projects, in order of appearance
+	members  map[string][]*todotxt.Task // tasks by project ("" for none)
+	depends  [][2]*todotxt.Task         // dependency edges (from the dependency)
+	subtasks [][2]*todotxt.Task         // parent links (from the parent)
+}
+
+// Fill colors of the nodes, by priority.
+var priorityColors = map[string]string{
+	"A": "#ff9999",
+	"B": "#ffcc99",
+	"C": "#ffff99",
+}
+
+const (
+	otherPriorityColor = "#cce5ff" // priorities D to Z
+	noPriorityColor    = "#ffffff"
+	completedColor     = "#dddddd"
+)
+
+// graphFormats renders a graph for each output format of the 'graph' command.
+var graphFormats = map[string]func(w io.Writer, g *taskGraph){
+	"dot":     renderDot,
+	"mermaid": renderMermaid,
+}
+
+// Builds the graph of the given tasks; the edges are limited to the tasks of
+// the graph, but are resolved within all the tasks.
+func newTaskGraph(all, shown todotxt.TaskList) *taskGraph {
+	g := &taskGraph{members: map[string][]*todotxt.Task{}}
+	nodes := map[uint64]*todotxt.Task{}
+	for i := range shown {
+		task := &shown[i]
+		nodes[task.Id] = task
+
+		// tasks with several projects go into the first one
+		project := ""
+		if len(task.Projects) > 0 {
+			project = task.Projects[0]
+		}
+		if _, ok := g.members[project]; !ok && project != "" {
+			g.groups = append(g.groups, project)
+		}
+		g.members[project] = append(g.members[project], task)
+	}
+
+	for i := range shown {
+		task := &shown[i]
+		for _, dep := range all.Dependencies(task) {
+			if node, ok := nodes[dep.Id]; ok {
+				g.depends = append(g.depends, [2]*todotxt.Task{node, task})
+			}
+		}
+		if parent := all.Parent(task); parent != nil {
+			if node, ok := nodes[parent.Id]; ok {
+				g.subtasks = append(g.subtasks, [2]*todotxt.Task{node, task})
+			}
+		}
+	}
+	return g
+}
+
+// Returns the label of the node of a task: its number and its text, without
+// the add-on tags already rendered as edges.
+func nodeLabel(task *todotxt.Task) string {
+	words := []string{}
+	for _, word := range strings.Fields(task.Todo) {
+		switch strings.SplitN(word, ":", 2)[0] {
+		case todotxt.DepTag, todotxt.ParentTag, todotxt.IdTag, todotxt.UuidTag:
+			continue
+		}
+		words = append(words, word)
+	}
+	text := strings.Join(words, " ")
+	if task.Priority != "" {
+		text = "(" + task.Priority + ") " + text
+	}
+	return fmt.Sprintf("%d: %s", task.Id, text)
+}
+
+// Returns the fill color of the node of a task.
+func nodeColor(task *todotxt.Task) string {
+	switch {
+	case task.Completed:
+		return completedColor
+	case task.Priority == "":
+		return noPriorityColor
+	}
+	if color, ok := priorityColors[task.Priority]; ok {
+		return color
+	}
+	return otherPriorityColor
+}
+
+// Renders a graph in the Graphviz DOT language.
+func renderDot(w io.Writer, g *taskGraph) {
+	quote := func(s string) string {
+		return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+	}
+	node := func(indent string, task *todotxt.Task) {
+		fmt.Fprintf(w, "%st%d [label=%s, fillcolor=%s];\n", indent, task.Id, quote(nodeLabel(task)),
+			quote(nodeColor(task)))
+	}
+
+	fmt.Fprintln(w, "digraph todo {")
+	fmt.Fprintln(w, "\trankdir=LR;")
+	fmt.Fprintln(w, "\tnode [shape=box, style=\"rounded,filled\"];")
+	for i, project := range g.groups {
+		fmt.Fprintf(w, "\tsubgraph cluster_%d {\n", i+1)
+		fmt.Fprintf(w, "\t\tlabel=%s;\n", quote(project))
+		for _, task := range g.members[project] {
+			node("\t\t", task)
+		}
+		fmt.Fprintln(w, "\t}")
+	}
+	for _, task := range g.members[""] {
+		node("\t", task)
+	}
+	for _, edge := range g.depends {
+		fmt.Fprintf(w, "\tt%d -> t%d;\n", edge[0].Id, edge[1].Id)
+	}
+	for _, edge := range g.subtasks {
+		fmt.Fprintf(w, "\tt%d -> t%d [style=dashed, arrowhead=empty];\n", edge[0].Id, edge[1].Id)
+	}
+	fmt.Fprintln(w, "}")
+}
+
+// Renders a graph as a Mermaid flowchart.
+func renderMermaid(w io.Writer, g *taskGraph) {
+	quote := func(s string) string {
+		return `"` + strings.Replace(s, `"`, "#quot;", -1) + `"`
+	}
+	styles := map[string][]uint64{}
+	node := func(indent string, task *todotxt.Task) {
+		fmt.Fprintf(w, "%st%d[%s]\n", indent, task.Id, quote(nodeLabel(task)))
+		color := nodeColor(task)
+		styles[color] = append(styles[color], task.Id)
+	}
+
+	fmt.Fprintln(w, "graph LR")
+	for i, project := range g.groups {
+		fmt.Fprintf(w, "\tsubgraph p%d[%s]\n", i+1, quote(project))
+		for _, task := range g.members[project] {
+			node("\t\t", task)
+		}
+		fmt.Fprintln(w, "\tend")
+	}
+	for _, task := range g.members[""] {
+		node("\t", task)
+	}
+	for _, edge := range g.depends {
+		fmt.Fprintf(w, "\tt%d --> t%d\n", edge[0].Id, edge[1].Id)
+	}
+	for _, edge := range g.subtasks {
+		fmt.Fprintf(w, "\tt%d -.-> t%d\n", edge[0].Id, edge[1].Id)
+	}
+
+	// one class per color, in a stable order
+	colors := []string{completedColor, noPriorityColor, otherPriorityColor}
+	for _, priority := range []string{"A", "B", "C"} {
+		colors = append(colors, priorityColors[priority])
+	}
+	for i, color := range colors {
+		if len(styles[color]) == 0 {
+			continue
+		}
+		nodes := []string{}
+		for _, id := range styles[color] {
+			nodes = append(nodes, fmt.Sprintf("t%d", id))
+		}
+		fmt.Fprintf(w, "\tclassDef c%d fill:%s\n", i, color)
+		fmt.Fprintf(w, "\tclass %s c%d\n", strings.Join(nodes, ","), i)
+	}
+}
+
+// Prints the graph of the tasks matching the filter in the given format.
+func (s *Session) graphAction(filter *taskFilter, format string) error {
+	render, ok := graphFormats[format]
+	if !ok {
+		return utils.NewError(utils.ErrUsage, "The formats are dot and mermaid.", "Unknown format %s.", format)
+	}
+
+	tasks, err := loadTasks(s.store("TODO_FILE"))
+	if err != nil {
+		return err
+	}
+	shown := filter.apply(visibleTasks(tasks, true, time.Now()))
+	render(s.IO.Out, newTaskGraph(tasks, shown))
+	return nil
+}
+
+func GetGraph(s *Session) cli.Command {
+
+	return cli.Command{
+		Name:  "graph",
+		Usage: "Renders the tasks as a graph of dependencies",
+		Description: `
+   This command prints the tasks matching the TERM(s) (all the tasks if no
+   TERM is given, see 'todo help list') as a graph, in the Graphviz DOT
+   language or as a Mermaid flowchart:
+
+      - every task is a node, grouped with the other tasks of its first
+        project (+project) and colored by priority;
+      - a task waiting on another one (dep: tag, see 'todo help list') is
+        linked to it by a solid arrow, from the dependency;
+      - a subtask (p: tag, see 'todo help add') is linked to its parent by a
+        dashed arrow, from the parent.
+
+   Hidden tasks (h:1) are left out.
+
+EXAMPLES:
+
+   Renders the tasks of the project +release as an SVG image with Graphviz:
+
+      $ todo graph +release | dot -Tsvg > release.svg
+
+   Prints a Mermaid flowchart, to paste into a Markdown document:
+
+      $ todo graph --format mermaid
+`,
+		Flags: []cli.Flag{
+			cli.StringFlag{"format", "dot", "selects the output format (dot, mermaid)"},
+		},
+		Action: s.action(func(c *cli.Context) error {
+			return s.graphAction(newTaskFilter(c.Args()), c.String("format"))
+		}),
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2014, Mauro Toffanin. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/codegangsta/cli"
+
+	"github.com/toffanin/go-todo/library/v1"
+	"github.com/toffanin/go-todo/utils"
+)
+
+// Prints the stable identifiers of the given tasks (all the tasks if refs is
+// empty), optionally assigning new identifiers to the tasks without one.
+func (s *Session) idsAction(refs []string, assign bool) error {
+	store := s.store("TODO_FILE")
+	tasks, err := loadTasks(store)
+	if err != nil {
+		return err
+	}
+
+	// backfill the missing identifiers
+	if assign {
+		n, err := tasks.AssignIds(s.idTag())
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			if err := saveTasks(store, tasks); err != nil {
+				return err
+			}
+		}
+		fmt.Fprintf(s.IO.Out, "TODO: %d identifiers assigned\n", n)
+	}
+
+	// collect the tasks to print
+	selected := []*todotxt.Task{}
+	if len(refs) == 0 {
+		for i := range tasks {
+			selected = append(selected, &tasks[i])
+		}
+	}
+	for _, ref := range refs {
+		task, err := findTask(tasks, ref)
+		if err != nil {
+			return err
+		}
+		selected = append(selected, task)
+	}
+
+	// print output
+	padding := len(strconv.Itoa(len(tasks)))
+	for _, task := range selected {
+		id := task.StableId()
+		if id == "" {
+			id = "-"
+		}
+		num := strconv.FormatUint(task.Id, 10)
+		fmt.Fprintf(s.IO.Out, "%s: %s\n", utils.PaddingLeft(num, "0", padding), id)
+	}
+	return nil
+}
+
+func GetIds(s *Session) cli.Command {
+
+	return cli.Command{
+		Name:  "ids",
+		Usage: "Displays or assigns the stable identifiers of the tasks",
+		Description: `
+   Task numbers are assigned by the position of the tasks inside todo.txt, so
+   they change whenever tasks are moved, deleted or archived. A task can carry
+   a stable identifier as an add-on tag (id:3f2a9c01 or uuid:...) which never
+   changes and can be used in place of the task number by every command.
+
+   This command prints the stable identifier of each ITEM (either a task
+   number or a stable identifier), or of all the tasks if no ITEM is given.
+
+   If the option '--assign' is set then a new stable identifier is added to
+   every task that doesn't have one yet.
+
+   New tasks receive a stable identifier automatically when TODOTXT_AUTO_ID is
+   set to 1; TODOTXT_ID_TAG selects the add-on tag to use (id or uuid).
+
+USAGE:
+
+   $ todo ids [--assign] [ITEM...]
+
+EXAMPLES:
+
+   Backfills the stable identifiers of an existing todo.txt file:
+
+      $ todo ids --assign
+
+   Prints the stable identifier of the tasks 3 and id:3f2a9c01:
+
+      $ todo ids 3 id:3f2a9c01
+`,
+		Flags: []cli.Flag{
+			cli.BoolFlag{"assign", "assigns a stable identifier to the tasks without one"},
+		},
+		Action: s.action(func(c *cli.Context) error {
+			return s.idsAction(c.Args(), c.Bool("assign"))
+		}),
+	}
+}
@@ -15,7 +15,7 @@ import (
 )
 
 // Create a todo.txt structure at the specified location (default destination is ".")
-func initAction(destination string) {
+func (s *Session) initAction(destination string) error {
 
 	var (
 		FileName = map[string]string{
@@ -48,6 +48,30 @@ export TODOTXT_DATE_ON_ADD=0
 
 # is same as option -f
 export TODOTXT_FORCE=0
+
+# adds a stable identifier (id:/uuid: tag) to new tasks
+export TODOTXT_AUTO_ID=0
+#export TODOTXT_ID_TAG="id"
+
+# caches the parsed task files, speeding up large todo.txt files
+export TODOTXT_CACHE=0
+#export TODOTXT_CACHE_DIR="$HOME/.cache/todo"
+
+# language and first day of the week of the date expressions (ex.: due:friday)
+#export TODOTXT_DATE_LOCALE="en"
+#export TODOTXT_WEEK_START="monday"
+
+# completion of the tasks with open subtasks: block (default) or cascade
+#export TODOTXT_COMPLETE_PARENT="block"
+
+# weights of the urgency of the tasks: factors (priority, due, age, blocking,
+# blocked), projects and contexts (see 'todo help next')
+#export TODOTXT_URGENCY="due:12 +work:2 @someday:-3"
+
+# raises the priority of the tasks due soon, with 'todo escalate' or on every
+# list if TODOTXT_AUTO_ESCALATE=1 (see 'todo help escalate')
+#export TODOTXT_ESCALATE="2d:B overdue:A"
+export TODOTXT_AUTO_ESCALATE=0
 `,
 			"todo":   "",
 			"done":   "",
@@ -63,8 +87,10 @@ export TODOTXT_FORCE=0
 	for _, filename := range FileName {
 		// sanitize the absolute path of the file
 		filePath, err := filepath.Abs(destination + filename)
-		//fmt.Printf("absolute path: %s\n", cfgFilePath)
-		utils.Check(err)
+		//fmt.Fprintf(s.IO.Out, "absolute path: %s\n", cfgFilePath)
+		if err != nil {
+			return utils.WrapError(utils.ErrIO, err, "")
+		}
 
 		ret, _ := utils.Exists(filePath)
 		if ret {
@@ -81,17 +107,21 @@ export TODOTXT_FORCE=0
 
 	// sanitize the absolute path of the destination
 	filePath, err := filepath.Abs(destination)
-	utils.Check(err)
+	if err != nil {
+		return utils.WrapError(utils.ErrIO, err, "")
+	}
 
 	// print first line of the action's summary
-	fmt.Printf("%s todo.txt structure in %s\n", message, filePath)
+	fmt.Fprintf(s.IO.Out, "%s todo.txt structure in %s\n", message, filePath)
 
 	// create the missing files of the todo.txt structure
 	for k, filename := range FileName {
 		// sanitize the absolute path of the file
 		filePath, err := filepath.Abs(destination + FileName[k])
-		utils.Check(err)
-		//fmt.Printf("absolute path: %s\n", filePath)
+		if err != nil {
+			return utils.WrapError(utils.ErrIO, err, "")
+		}
+		//fmt.Fprintf(s.IO.Out, "absolute path: %s\n", filePath)
 
 		// Open file
 		file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
@@ -100,26 +130,32 @@ export TODOTXT_FORCE=0
 		// if there aren't errors, write a new file with default values
 		if err == nil {
 			size, err := file.WriteString(FileTemplate[k])
-			utils.Check(err)
+			if err != nil {
+				return utils.WrapError(utils.ErrIO, err, "")
+			}
 
 			// sync / flush file
 			file.Sync()
 
 			// print a small summary
-			fmt.Printf("%s [%s] (%d bytes)\n", filename, "new", size)
+			fmt.Fprintf(s.IO.Out, "%s [%s] (%d bytes)\n", filename, "new", size)
 			continue
 		}
 
 		// file exists, there is nothing to write
 		if os.IsExist(err) {
 			// print a small summary
-			fmt.Printf("%s [%s]\n", filename, "exists")
+			fmt.Fprintf(s.IO.Out, "%s [%s]\n", filename, "exists")
 			continue
 		}
+
+		// brace yourself: unknown errors are coming
+		return utils.WrapError(utils.ErrIO, err, "")
 	}
+	return nil
 }
 
-func GetInit() cli.Command {
+func GetInit(s *Session) cli.Command {
 
 	return cli.Command{
 		Name:  "init",
@@ -138,13 +174,13 @@ func GetInit() cli.Command {
 		Flags: []cli.Flag{
 			cli.StringFlag{"dest, d", "/path/to/your/dir", "specifies a different destination path"},
 		},
-		Action: func(c *cli.Context) {
+		Action: s.action(func(c *cli.Context) error {
 			destination := "."
 			if c.IsSet("dest") {
 				//fmt.Println("dest:", c.String("dest"))
 				destination = c.String("dest")
 			}
-			initAction(destination)
-		},
+			return s.initAction(destination)
+		}),
 	}
 }
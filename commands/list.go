@@ -6,8 +6,9 @@ package commands
 
 import (
 	"fmt"
-	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/codegangsta/cli"
 
@@ -15,46 +16,108 @@ import (
 	"github.com/toffanin/go-todo/utils"
 )
 
-// scan todo.txt file and collect all the tasks
-func listAllTasks(file *os.File) {
-	reader := todotxt.NewReader(file)
-	tasks, err := reader.ReadAll()
-	utils.Check(err)
+// listOptions holds the options of the command list.
+type listOptions struct {
+	all     bool // lists the tasks deferred by a threshold date too
+	tree    bool // lists the subtasks under their parent
+	ready   bool // lists only the tasks without open dependencies
+	blocked bool // lists only the tasks with open dependencies
+}
+
+// print the tasks of a task list matching the filter
+func (s *Session) listTasks(tasks todotxt.TaskList, filter *taskFilter, opts listOptions) {
 	//fmt.Printf("Tasks: %st\n", tasks)
 
-	// TODO: run the filter command, then sort and mangle output some more
+	// Build and apply the filter, then sort the output
+	shown := filter.apply(visibleTasks(tasks, opts.all, time.Now()))
+	if opts.ready || opts.blocked {
+		shown = dependencyTasks(tasks, shown, opts.blocked)
 
-	// TODO: Build and apply the filter
+		// the tasks of a cycle are blocked forever
+		if cycle := tasks.DependencyCycle(); cycle != nil {
+			fmt.Fprintf(s.IO.Err, "TODO: Warning: dependency cycle between tasks %s.\n", cyclePath(cycle))
+		}
+	}
+	sortTasks(shown, filter)
 
 	// print output
-	ntasks := reader.Len()
+	ntasks := uint64(len(tasks))
 	padding := len(strconv.FormatUint(ntasks, 10))
-	i := uint64(0)
-	for i < ntasks {
-		s := strconv.FormatUint(tasks[i].Id, 10)
+	printTask := func(task *todotxt.Task, depth int) error {
+		num := strconv.FormatUint(task.Id, 10)
 		// TODO: console colours
-		fmt.Printf("%s: %s\n", utils.PaddingLeft(s, "0", padding), tasks[i].Todo)
-		i++
+		text := task.String()
+		if opts.blocked {
+			text += fmt.Sprintf(" (blocked by %s)", taskNumbers(tasks.Blockers(task)))
+		}
+		fmt.Fprintf(s.IO.Out, "%s: %s%s\n", utils.PaddingLeft(num, "0", padding),
+			strings.Repeat("  ", depth), text)
+		return nil
+	}
+	if opts.tree {
+		shown.Walk(printTask)
+	} else {
+		for i := range shown {
+			printTask(&shown[i], 0)
+		}
 	}
 
 	// if required print verbose info
-	verbose, err := strconv.Atoi(utils.GetSetting("TODOTXT_VERBOSE"))
-	utils.Check(err)
+	verbose := s.Config.Verbose
 
 	switch {
 	case verbose == 0:
 		return
 	case verbose >= 1:
 		if verbose > 1 {
-			// TODO
-			fmt.Printf("TODO DEBUG: Filter Command was: %#t\n", nil)
+			fmt.Fprintf(s.IO.Out, "TODO DEBUG: Filter Command was: %s\n", filter)
+		}
+		fmt.Fprintln(s.IO.Out, "--")
+		fmt.Fprintf(s.IO.Out, "TODO: %d of %d tasks shown\n", len(shown), ntasks)
+	}
+}
+
+// Returns the tasks of todo.txt, whose priorities are escalated first if
+// TODOTXT_AUTO_ESCALATE is set; the changes are logged to the error output.
+func (s *Session) listedTasks() (todotxt.TaskList, error) {
+	if !s.Config.AutoEscalate || s.Config.Escalate == "" {
+		return loadTasks(s.store("TODO_FILE"))
+	}
+	rules, err := s.escalationRules()
+	if err != nil {
+		return nil, err
+	}
+	tasks, _, err := s.escalateTasks(rules, s.IO.Err, false)
+	return tasks, err
+}
+
+// Returns the tasks to list on the given day: the hidden tasks (h:1) are
+// left out, and so are the tasks deferred by a threshold date (t:) unless all
+// is true.
+func visibleTasks(tasks todotxt.TaskList, all bool, day time.Time) todotxt.TaskList {
+	visible := todotxt.TaskList{}
+	for i := range tasks {
+		if tasks[i].Hidden || !all && tasks[i].Deferred(day) {
+			continue
 		}
-		fmt.Println("--")
-		fmt.Printf("TODO: %d of %d tasks shown\n", ntasks, ntasks)
+		visible = append(visible, tasks[i])
 	}
+	return visible
 }
 
-func GetList() cli.Command {
+// Returns the open tasks of shown which are blocked by open dependencies
+// within tasks if blocked is true, or the ones ready to be started otherwise.
+func dependencyTasks(tasks, shown todotxt.TaskList, blocked bool) todotxt.TaskList {
+	selected := todotxt.TaskList{}
+	for i := range shown {
+		if !shown[i].Completed && tasks.Blocked(&shown[i]) == blocked {
+			selected = append(selected, shown[i])
+		}
+	}
+	return selected
+}
+
+func GetList(s *Session) cli.Command {
 
 	return cli.Command{
 		Name:      "list",
@@ -75,6 +138,27 @@ func GetList() cli.Command {
    Logical operator 'and' is always assumed where the operator is omitted.
    Quotation marks around a logical statement are optional.
 
+   If TODOTXT_AUTO_ESCALATE is set then the priorities of the tasks due soon
+   are escalated first (see 'todo help escalate').
+
+   Tasks with a threshold date in the future (t:YYYY-MM-DD) can't be started
+   yet, so they are listed only if the option '--all' is set. Tasks tagged
+   with h:1 are never listed.
+
+   If the option '--tree' is set then the subtasks (see 'todo help add') are
+   listed under their parent, indented by depth. Subtasks whose parent isn't
+   listed are listed at the top level.
+
+   A task can wait on other tasks, listed by their stable identifier with the
+   add-on tag dep: (ex.: dep:3f2a9c01, see 'todo help ids'). If the option
+   '--ready' is set then 'list' displays only the open tasks without open
+   dependencies, while '--blocked' displays only the open tasks with open
+   dependencies, along with the tasks blocking them.
+
+   If the option '--watch' is set then 'list' keeps running and displays the
+   tasks again, with the same TERM(s) and sorting, whenever TODO_FILE or
+   DONE_FILE change.
+
 OPERATORS:
 
    Logical operators listed in order of decreasing precedence:
@@ -132,27 +216,50 @@ EXAMPLES:
       > Buy eggs, cheese and milk @grocery
       > Buy a cake for Friday's dinner party with friends @grocery
       > Cook an omelet with eggs, cheese and veggies for Mary's @lunch
+
+   Keeps the list of all the tasks with the context '@grocery' on screen,
+   refreshing it whenever todo.txt changes:
+
+      $ todo list --watch @grocery
 `,
-		Action: func(c *cli.Context) {
+		BashComplete: s.completeTags,
+		Flags: []cli.Flag{
+			cli.BoolFlag{"watch, w", "refreshes the listing whenever TODO_FILE or DONE_FILE change"},
+			cli.BoolFlag{"all, a", "lists the tasks with a threshold date in the future too"},
+			cli.BoolFlag{"tree", "lists the subtasks under their parent"},
+			cli.BoolFlag{"ready", "lists only the tasks without open dependencies (dep:)"},
+			cli.BoolFlag{"blocked", "lists only the tasks with open dependencies (dep:)"},
+		},
+		Action: s.action(func(c *cli.Context) error {
 			// collect all the user-submitted arguments in an array
 			args := c.Args()
-			nargs := len(args)
 
-			// open todo.txt file
-			todoFile := utils.GetSetting("TODO_FILE")
-			file, err := os.Open(todoFile)
-			utils.Check(err)
-			defer file.Close()
+			// build the filter once, so that it is preserved across refreshes
+			filter := newTaskFilter(args)
+			opts := listOptions{all: c.Bool("all"), tree: c.Bool("tree"),
+				ready: c.Bool("ready"), blocked: c.Bool("blocked")}
+			if opts.ready && opts.blocked {
+				return utils.NewError(utils.ErrUsage, "Use either --ready or --blocked.",
+					"Options --ready and --blocked are mutually exclusive.")
+			}
+			render := func() error {
+				tasks, err := s.listedTasks()
+				if err != nil {
+					return err
+				}
+				s.listTasks(tasks, filter, opts)
+				return nil
+			}
 
-			switch nargs {
-			case 0:
-				listAllTasks(file)
+			if c.Bool("watch") {
+				return s.watchListing(render)
 			}
 
 			// debugging
 			/*fmt.Println("[todo:list] ConfPaths (filtered): ", utils.ConfPaths)
 			fmt.Println("[todo:list] Settings: (filtered): ", utils.Settings)*/
 
-		},
+			return render()
+		}),
 	}
 }
@@ -0,0 +1,144 @@
+// Copyright (c) 2014, Mauro Toffanin. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"fmt"
+
+	"github.com/codegangsta/cli"
+
+	"github.com/toffanin/go-todo/library/v1"
+)
+
+// print the tags of a tag index, restricted to the tasks matching the filter
+func (s *Session) listTags(index *todotxt.Index, tags map[string][]uint64, filter *taskFilter) {
+	// without a filter the tag index is enough: tasks aren't needed
+	if filter.empty() {
+		for _, tag := range todotxt.SortedKeys(tags) {
+			fmt.Fprintln(s.IO.Out, tag)
+		}
+		return
+	}
+
+	matched := map[uint64]bool{}
+	for i := range index.Tasks {
+		if filter.match(&index.Tasks[i]) {
+			matched[index.Tasks[i].Id] = true
+		}
+	}
+	for _, tag := range todotxt.SortedKeys(tags) {
+		for _, id := range tags[tag] {
+			if matched[id] {
+				fmt.Fprintln(s.IO.Out, tag)
+				break
+			}
+		}
+	}
+}
+
+// print the projects and the contexts of todo.txt for the bash completion
+func (s *Session) completeTags(c *cli.Context) {
+	// errors can't be reported while completing, there is nothing to complete
+	index, err := loadIndex(s.store("TODO_FILE"))
+	if err != nil {
+		return
+	}
+	for _, tag := range todotxt.SortedKeys(index.Projects) {
+		fmt.Fprintln(s.IO.Out, tag)
+	}
+	for _, tag := range todotxt.SortedKeys(index.Contexts) {
+		fmt.Fprintln(s.IO.Out, tag)
+	}
+}
+
+func GetListproj(s *Session) cli.Command {
+
+	return cli.Command{
+		Name:      "listproj",
+		ShortName: "lsprj",
+		Usage:     "Lists all the projects in todo.txt",
+		Description: `
+   This command lists all the projects (terms that start with a + sign) used
+   by the tasks inside a todo.txt file, one per line.
+
+   If one or more TERM(s) is given as arguments, 'listproj' displays only the
+   projects of the tasks whose text contains TERM(s). The same logical
+   operators of the 'list' command are supported.
+
+   If the option '--watch' is set then 'listproj' keeps running and displays
+   the projects again whenever TODO_FILE or DONE_FILE change.
+
+EXAMPLES:
+
+      $ todo listproj
+      $ todo listproj @grocery
+`,
+		BashComplete: s.completeTags,
+		Flags: []cli.Flag{
+			cli.BoolFlag{"watch, w", "refreshes the listing whenever TODO_FILE or DONE_FILE change"},
+		},
+		Action: s.action(func(c *cli.Context) error {
+			filter := newTaskFilter(c.Args())
+			render := func() error {
+				index, err := loadIndex(s.store("TODO_FILE"))
+				if err != nil {
+					return err
+				}
+				s.listTags(index, index.Projects, filter)
+				return nil
+			}
+
+			if c.Bool("watch") {
+				return s.watchListing(render)
+			}
+			return render()
+		}),
+	}
+}
+
+func GetListcon(s *Session) cli.Command {
+
+	return cli.Command{
+		Name:      "listcon",
+		ShortName: "lsc",
+		Usage:     "Lists all the contexts in todo.txt",
+		Description: `
+   This command lists all the contexts (terms that start with an @ sign) used
+   by the tasks inside a todo.txt file, one per line.
+
+   If one or more TERM(s) is given as arguments, 'listcon' displays only the
+   contexts of the tasks whose text contains TERM(s). The same logical
+   operators of the 'list' command are supported.
+
+   If the option '--watch' is set then 'listcon' keeps running and displays
+   the contexts again whenever TODO_FILE or DONE_FILE change.
+
+EXAMPLES:
+
+      $ todo listcon
+      $ todo listcon +cleaning
+`,
+		BashComplete: s.completeTags,
+		Flags: []cli.Flag{
+			cli.BoolFlag{"watch, w", "refreshes the listing whenever TODO_FILE or DONE_FILE change"},
+		},
+		Action: s.action(func(c *cli.Context) error {
+			filter := newTaskFilter(c.Args())
+			render := func() error {
+				index, err := loadIndex(s.store("TODO_FILE"))
+				if err != nil {
+					return err
+				}
+				s.listTags(index, index.Contexts, filter)
+				return nil
+			}
+
+			if c.Bool("watch") {
+				return s.watchListing(render)
+			}
+			return render()
+		}),
+	}
+}
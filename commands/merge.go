@@ -0,0 +1,129 @@
+// Copyright (c) 2014, Mauro Toffanin. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/codegangsta/cli"
+
+	"github.com/toffanin/go-todo/library/v1"
+	"github.com/toffanin/go-todo/utils"
+)
+
+// Merges the changes of ours and theirs into the file ours, returning the
+// number of conflicts.
+func (s *Session) mergeAction(base, ours, theirs string) (int, error) {
+	versions := []todotxt.TaskList{}
+	for _, file := range []string{base, ours, theirs} {
+		tasks, err := loadTasks(todotxt.NewFileStore(file))
+		if err != nil {
+			return 0, err
+		}
+		versions = append(versions, tasks)
+	}
+	merged, conflicts := todotxt.Merge(versions[0], versions[1], versions[2])
+
+	// map every conflicting task to its conflict
+	marked := map[int]todotxt.Conflict{}
+	for _, conflict := range conflicts {
+		marked[conflict.Index] = conflict
+	}
+
+	// git expects the merged result inside the file 'ours'
+	file, err := os.OpenFile(ours, os.O_WRONLY|os.O_TRUNC|os.O_CREATE, 0600)
+	if err != nil {
+		return 0, utils.WrapError(utils.ErrIO, err, "")
+	}
+	defer file.Close()
+
+	// use buffered I/O
+	writer := bufio.NewWriter(file)
+	for i := range merged {
+		conflict, ok := marked[i]
+		if !ok {
+			if _, err = writer.WriteString(merged[i].String() + "\n"); err != nil {
+				return 0, utils.WrapError(utils.ErrIO, err, "")
+			}
+			continue
+		}
+
+		// surround the conflicting task with conflict markers
+		lines := []string{"<<<<<<< ours"}
+		if conflict.Ours != nil {
+			lines = append(lines, conflict.Ours.String())
+		}
+		lines = append(lines, "=======")
+		if conflict.Theirs != nil {
+			lines = append(lines, conflict.Theirs.String())
+		}
+		lines = append(lines, ">>>>>>> theirs")
+		if _, err = writer.WriteString(strings.Join(lines, "\n") + "\n"); err != nil {
+			return 0, utils.WrapError(utils.ErrIO, err, "")
+		}
+
+		// print a small summary
+		fmt.Fprintf(s.IO.Out, "CONFLICT (%s): %s\n", strings.Join(conflict.Fields, ", "), merged[i].Identity())
+	}
+	if err = writer.Flush(); err != nil {
+		return 0, utils.WrapError(utils.ErrIO, err, "")
+	}
+	return len(conflicts), nil
+}
+
+func GetMergeDriver(s *Session) cli.Command {
+
+	return cli.Command{
+		Name:  "merge-driver",
+		Usage: "Merges two versions of a todo.txt file (git merge driver)",
+		Description: `
+   This command performs a three-way merge of the todo.txt files OURS and
+   THEIRS, both derived from the common ancestor BASE, and writes the result
+   into OURS.
+
+   Unlike a line-based merge, tasks are matched by their text and the changes
+   to completion, priority, projects, contexts and add-on tags are merged field
+   by field. Only a task changed in different ways on both sides is reported
+   as a conflict and surrounded with conflict markers; in such a case the
+   command exits with a non-zero status.
+
+USAGE:
+
+   $ todo merge-driver BASE OURS THEIRS
+
+EXAMPLES:
+
+   Configures 'todo' as the merge driver of your todo.txt files in git:
+
+      $ git config merge.todotxt.name "todo.txt merge driver"
+      $ git config merge.todotxt.driver "todo merge-driver %O %A %B"
+
+   and then add the following lines to your .gitattributes file:
+
+      todo.txt merge=todotxt
+      done.txt merge=todotxt
+`,
+		Action: s.action(func(c *cli.Context) error {
+			// collect all the user-submitted arguments in an array
+			args := c.Args()
+
+			// check incorrect usage of the command
+			if len(args) != 3 {
+				cli.ShowCommandHelp(c, "merge-driver")
+				return utils.NewError(utils.ErrUsage, "Usage: todo merge-driver BASE OURS THEIRS",
+					"Detected wrong options with command \"merge-driver\"")
+			}
+
+			conflicts, err := s.mergeAction(args[0], args[1], args[2])
+			if err == nil && conflicts > 0 {
+				err = utils.NewError(utils.ErrFailure, "", "%d conflicts found", conflicts)
+			}
+			return err
+		}),
+	}
+}
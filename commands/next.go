@@ -0,0 +1,201 @@
+// Copyright (c) 2014, Mauro Toffanin. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/codegangsta/cli"
+
+	"github.com/toffanin/go-todo/library/v1"
+	"github.com/toffanin/go-todo/utils"
+)
+
+// Number of tasks shown by the command next by default.
+const nextTasks = 5
+
+// rankedTask is a task along with the factors of its urgency.
+type rankedTask struct {
+	task    *todotxt.Task
+	factors []todotxt.UrgencyFactor
+	urgency float64
+}
+
+// tasksByUrgency sorts tasks by decreasing urgency.
+type tasksByUrgency []rankedTask
+
+func (t tasksByUrgency) Len() int           { return len(t) }
+func (t tasksByUrgency) Swap(i, j int)      { t[i], t[j] = t[j], t[i] }
+func (t tasksByUrgency) Less(i, j int) bool { return t[i].urgency > t[j].urgency }
+
+// Returns the urgency model of the tasks on the given day, with the weights
+// of TODOTXT_URGENCY: a list of NAME:WEIGHT pairs separated by spaces or
+// commas, where NAME is a factor of the urgency, a project or a context.
+func (s *Session) urgencyModel(tasks todotxt.TaskList, now time.Time) (*todotxt.UrgencyModel, error) {
+	m := todotxt.NewUrgencyModel(tasks, now)
+	hint := "Please set TODOTXT_URGENCY to a list of NAME:WEIGHT (ex.: due:12 +work:2), " +
+		"where NAME is priority, due, age, blocking, blocked, a project or a context."
+
+	separator := func(r rune) bool { return r == ',' || unicode.IsSpace(r) }
+	for _, field := range strings.FieldsFunc(s.Config.Urgency, separator) {
+		i := strings.LastIndex(field, ":")
+		if i <= 0 {
+			return nil, utils.NewError(utils.ErrConfig, hint, "Invalid urgency weight %q.", field)
+		}
+		name := field[:i]
+		weight, err := strconv.ParseFloat(field[i+1:], 64)
+		if err != nil {
+			return nil, utils.NewError(utils.ErrConfig, hint, "Invalid urgency weight %q.", field)
+		}
+
+		switch {
+		case strings.HasPrefix(name, "+") || strings.HasPrefix(name, "@"):
+			m.Tags[name] = weight
+		default:
+			if _, ok := m.Weights[name]; !ok {
+				return nil, utils.NewError(utils.ErrConfig, hint, "Unknown urgency factor %q.", name)
+			}
+			m.Weights[name] = weight
+		}
+	}
+	return m, nil
+}
+
+// Prints the n most urgent open tasks matching the filter, along with the
+// factors of their urgency if verbose.
+func (s *Session) nextAction(filter *taskFilter, n int) error {
+	tasks, err := loadTasks(s.store("TODO_FILE"))
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	m, err := s.urgencyModel(tasks, now)
+	if err != nil {
+		return err
+	}
+
+	// rank the open tasks which can be started today
+	shown := filter.apply(visibleTasks(tasks, false, now))
+	ranked := tasksByUrgency{}
+	for i := range shown {
+		task := &shown[i]
+		if task.Completed {
+			continue
+		}
+		factors := m.Factors(task)
+		ranked = append(ranked, rankedTask{task, factors, task.Urgency(m)})
+	}
+	sort.Stable(ranked)
+	if len(ranked) > n {
+		ranked = ranked[:n]
+	}
+
+	padding := len(strconv.Itoa(len(tasks)))
+	for _, r := range ranked {
+		num := strconv.FormatUint(r.task.Id, 10)
+		fmt.Fprintf(s.IO.Out, "%s: %s (urgency %.1f)\n", utils.PaddingLeft(num, "0", padding),
+			r.task.String(), r.urgency)
+		if s.Config.Verbose > 0 {
+			for _, factor := range r.factors {
+				fmt.Fprintf(s.IO.Out, "%s  %-12s %+6.1f\n", strings.Repeat(" ", padding), factor.Name,
+					factor.Value)
+			}
+		}
+	}
+	return nil
+}
+
+func GetNext(s *Session) cli.Command {
+
+	return cli.Command{
+		Name:  "next",
+		Usage: "Displays the most urgent tasks",
+		Description: `
+   This command lists the N most urgent open tasks (5 if N is omitted) among
+   the tasks matching the TERM(s) (see 'todo help list'), from the most urgent
+   one. Tasks deferred by a threshold date (t:) and hidden tasks (h:1) are
+   left out.
+
+   The urgency of a task is a sum of factors, each one being a weight
+   multiplied by a ratio between 0 and 1:
+
+      priority   (A) 1, (B) 0.65, (C) 0.3, (D) to (Z) 0.1        weight 6
+      due        0.2 up to two weeks before the due date (due:),
+                 rising up to 1 a week after it                   weight 12
+      age        0 on the created date, up to 1 a year after it   weight 2
+      blocking   1 if open tasks depend on the task (dep:)        weight 8
+      blocked    1 if the task depends on open tasks (dep:)       weight -5
+
+   plus a weight for every project and context of the task (0 by default).
+
+   The weights are set by TODOTXT_URGENCY, as a list of NAME:WEIGHT pairs
+   where NAME is a factor, a project or a context. If TODOTXT_VERBOSE is set
+   (or the global option -v), the factors of every task are listed under it.
+
+   If the option '--watch' is set then 'next' keeps running and ranks the
+   tasks again whenever TODO_FILE or DONE_FILE change.
+
+EXAMPLES:
+
+   Raises the weight of the due dates and of the project +work, and lowers the
+   urgency of the tasks with the context @someday, in todo.cfg:
+
+      export TODOTXT_URGENCY="due:20 +work:2 @someday:-3"
+
+   Given this todo.txt as a reference, on 2014-06-04:
+      (A) Send the report due:2014-06-02 +work
+      2014-05-05 Book the meeting room due:2014-06-10 +work @office
+      Update the wiki +work @someday
+      Call mom @phone
+
+   Lists the 3 most urgent tasks of the project +work, with their factors:
+
+      $ todo -v next 3 +work
+      > 1: (A) Send the report due:2014-06-02 +work (urgency 24.2)
+      >    priority       +6.0
+      >    due           +16.2
+      >    +work          +2.0
+      > 2: 2014-05-05 Book the meeting room due:2014-06-10 +work @office (urgency 12.3)
+      >    due           +10.1
+      >    age            +0.2
+      >    +work          +2.0
+      > 3: Update the wiki +work @someday (urgency -1.0)
+      >    +work          +2.0
+      >    @someday       -3.0
+`,
+		BashComplete: s.completeTags,
+		Flags: []cli.Flag{
+			cli.BoolFlag{"watch, w", "refreshes the listing whenever TODO_FILE or DONE_FILE change"},
+		},
+		Action: s.action(func(c *cli.Context) error {
+			args := c.Args()
+			n := nextTasks
+			if len(args) > 0 {
+				if value, err := strconv.Atoi(args[0]); err == nil {
+					if value <= 0 {
+						return utils.NewError(utils.ErrUsage, "Usage: todo next [N] [TERM...]",
+							"Invalid number of tasks %d.", value)
+					}
+					n = value
+					args = args[1:]
+				}
+			}
+			filter := newTaskFilter(args)
+			render := func() error {
+				return s.nextAction(filter, n)
+			}
+
+			if c.Bool("watch") {
+				return s.watchListing(render)
+			}
+			return render()
+		}),
+	}
+}
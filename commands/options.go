@@ -0,0 +1,48 @@
+// Copyright (c) 2014, Mauro Toffanin. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"github.com/codegangsta/cli"
+
+	"github.com/toffanin/go-todo/utils"
+)
+
+// A boolOption binds a boolean setting to the global flags which enable and
+// disable it.
+type boolOption struct {
+	setting string
+	on      string // flag setting the option to 1
+	off     string // flag setting the option to 0; empty if there is none
+}
+
+// boolOptions lists the boolean settings which can be changed by the global
+// flags of the command line.
+var boolOptions = []boolOption{
+	{"TODOTXT_DATE_ON_ADD", "t", "T"},
+	{"TODOTXT_FORCE", "f", ""},
+}
+
+// Resolves the boolean options of the command line: the global flags take
+// precedence over the environment variables, which take precedence over the
+// configuration files (see utils.Loader). Commands read the resolved options
+// from the configuration of the session only.
+func (s *Session) resolveOptions(c *cli.Context) error {
+	for _, option := range boolOptions {
+		on := c.GlobalBool(option.on)
+		off := option.off != "" && c.GlobalBool(option.off)
+
+		switch {
+		case on && off:
+			return utils.NewError(utils.ErrUsage, "",
+				"The options -%s and -%s can't be used together.", option.on, option.off)
+		case on:
+			s.Config.Override(option.setting, "1", utils.OriginCommandLine)
+		case off:
+			s.Config.Override(option.setting, "0", utils.OriginCommandLine)
+		}
+	}
+	return nil
+}
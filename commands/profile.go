@@ -0,0 +1,132 @@
+// Copyright (c) 2014, Mauro Toffanin. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"fmt"
+
+	"github.com/codegangsta/cli"
+
+	"github.com/toffanin/go-todo/utils"
+)
+
+// Lists the profiles, selects the default profile or prints the current one.
+func (s *Session) profileAction(args []string, file string) error {
+	action := ""
+	if len(args) > 0 {
+		action = args[0]
+	}
+
+	switch {
+	case action == "" && len(args) == 0:
+		if s.Config.Profile == "" {
+			fmt.Fprintln(s.IO.Out, "TODO: No profile selected.")
+			return nil
+		}
+		fmt.Fprintln(s.IO.Out, s.Config.Profile)
+
+	case action == "list" && len(args) == 1:
+		for _, name := range s.Config.Profiles {
+			marker := " "
+			if name == s.Config.Profile {
+				marker = "*"
+			}
+			fmt.Fprintf(s.IO.Out, "%s %s\n", marker, name)
+		}
+		if len(s.Config.Profiles) == 0 {
+			fmt.Fprintln(s.IO.Out, "TODO: No profiles defined.")
+		}
+
+	case action == "use" && len(args) == 2:
+		name := args[1]
+		found := false
+		for _, profile := range s.Config.Profiles {
+			found = found || profile == name
+		}
+		if !found {
+			return utils.NewError(utils.ErrFailure, "The available profiles are listed by 'todo profile list'.",
+				"Unknown profile %s.", name)
+		}
+
+		cfg, err := utils.ReadConfigFile(s.configFile(file, "TODOTXT_PROFILE"))
+		if err != nil {
+			return utils.WrapError(utils.ErrIO, err, "")
+		}
+		cfg.Set("TODOTXT_PROFILE", name)
+		if err := cfg.Save(); err != nil {
+			return utils.WrapError(utils.ErrIO, err, "")
+		}
+
+		if s.Config.Origin("TODOTXT_PROFILE") == utils.OriginEnvironment {
+			fmt.Fprintln(s.IO.Out, "TODO: TODOTXT_PROFILE is set in the environment, which takes precedence.")
+		}
+		fmt.Fprintf(s.IO.Out, "TODO: Profile %s selected in %s\n", name, cfg.Path)
+
+	default:
+		return utils.NewError(utils.ErrUsage, "Usage: todo profile [list | use NAME [--file PATH]]",
+			"Detected wrong options with command \"profile\"")
+	}
+	return nil
+}
+
+func GetProfile(s *Session) cli.Command {
+
+	return cli.Command{
+		Name:  "profile",
+		Usage: "Lists and selects the profiles of the configuration",
+		Description: `
+   A profile is a named set of settings (ex.: its own TODO_DIR) which is
+   loaded on top of the configuration files, so that the same 'todo' command
+   can manage several todo.txt workspaces (ex.: personal, team and on-call).
+
+   A profile is defined either by a section of a configuration file, which
+   lasts until the next section or the end of the file:
+
+   # This is just an example
+   export TODO_DIR="$HOME/todo"
+
+   [profile work]
+   export TODO_DIR="$HOME/work/todo"
+   export TODOTXT_AUTO_ID=1
+
+   or by a file named after the profile (ex.: work.cfg) inside the directory
+   $HOME/.todo/profiles or $XDG_CONFIG_HOME/todo/profiles.
+
+   The profile is selected with the global option '--profile NAME', or else
+   with the setting TODOTXT_PROFILE (in the environment or in a configuration
+   file). This command prints the current profile, or else:
+
+   list         lists all the profiles, marking the current one with '*'
+   use NAME     selects NAME by default, setting TODOTXT_PROFILE in the
+                configuration file with the highest precedence (or in the
+                file selected with '--file')
+
+USAGE:
+
+   $ todo profile
+   $ todo profile list
+   $ todo profile use NAME [--file PATH]
+
+EXAMPLES:
+
+   Adds a task to the on-call list, whatever the default profile:
+
+      $ todo --profile oncall add "Check the backups"
+
+   Stops using a default profile:
+
+      $ todo config unset TODOTXT_PROFILE
+`,
+		Flags: []cli.Flag{
+			cli.StringFlag{"file", "", "selects the configuration file to change"},
+		},
+		Action: s.action(func(c *cli.Context) error {
+			// collect all the user-submitted arguments in an array
+			args, file := fileOption(c.Args(), c.String("file"))
+
+			return s.profileAction(args, file)
+		}),
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright (c) 2014, Mauro Toffanin. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package commands implements the commands of the todo.txt CLI.
+//
+// Every command is built for a Session, which carries the configuration and
+// the storage backends used by the command, so that applications can embed
+// the commands with more than one configuration at the same time:
+//
+//	cfg, err := utils.NewLoader().Load()
+//	if err != nil {
+//	  // handle the error
+//	}
+//	session := commands.NewSession(cfg)
+//	session.IO = utils.NewIO(in, out, out) // optional, for embedding
+//	app.Commands = []cli.Command{
+//	  commands.GetAdd(session),
+//	  commands.GetList(session),
+//	}
+//	app.Run(os.Args)
+//	if session.Err != nil {
+//	  utils.PrintError(session.IO.Err, session.Err, cfg.Verbose)
+//	  os.Exit(utils.ExitCode(session.Err))
+//	}
+package commands
+
+import (
+	"github.com/codegangsta/cli"
+
+	"github.com/toffanin/go-todo/library/v1"
+	"github.com/toffanin/go-todo/utils"
+)
+
+// A Session holds the state shared by the commands of an application.
+type Session struct {
+	Config *utils.Config // Configuration used by the commands
+	IO     *utils.IO     // Streams used by the commands and their prompts
+
+	// StoreFactory returns the storage backend of the task list named by a
+	// setting (ex.: TODO_FILE, DONE_FILE).
+	//
+	// By default every task list is a todo.txt file located at the path held
+	// by the setting. Applications embedding the commands can replace
+	// StoreFactory to keep the tasks elsewhere, for example inside a
+	// todotxt.MemoryStore.
+	StoreFactory func(setting string) todotxt.Store
+
+	// Err holds the error returned by the last command run, if any.
+	Err error
+}
+
+// NewSession returns a Session for the given configuration, which uses the
+// standard streams of the process.
+func NewSession(cfg *utils.Config) *Session {
+	s := &Session{Config: cfg, IO: utils.StdIO()}
+	s.StoreFactory = s.fileStore
+	return s
+}
+
+// action adapts a command returning an error to a cli action, storing the
+// error inside the session. The options of the command line are resolved
+// before running the command.
+func (s *Session) action(fn func(c *cli.Context) error) func(c *cli.Context) {
+	return func(c *cli.Context) {
+		if s.Err = s.resolveOptions(c); s.Err == nil {
+			s.Err = fn(c)
+		}
+	}
+}
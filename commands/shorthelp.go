@@ -8,7 +8,7 @@ import (
 	"github.com/codegangsta/cli"
 )
 
-func GetShorthelp() cli.Command {
+func GetShorthelp(s *Session) cli.Command {
 
 	return cli.Command{
 		Name:  "shorthelp",
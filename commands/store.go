@@ -0,0 +1,53 @@
+// Copyright (c) 2014, Mauro Toffanin. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/toffanin/go-todo/library/v1"
+)
+
+// Returns the todo.txt file located at the path held by setting.
+func (s *Session) fileStore(setting string) todotxt.Store {
+	store := todotxt.NewFileStore(s.Config.Get(setting))
+
+	// honour TODOTXT_CACHE by caching the parsed files
+	if s.Config.Cache {
+		store.Cache = todotxt.NewCache(s.cacheDir())
+	}
+	return store
+}
+
+// Returns the storage backend of the task list named by setting.
+func (s *Session) store(setting string) todotxt.Store {
+	return s.StoreFactory(setting)
+}
+
+// Returns the directory of the cache of the parsed files: TODOTXT_CACHE_DIR
+// if set, otherwise $XDG_CACHE_HOME/todo or $HOME/.cache/todo.
+func (s *Session) cacheDir() string {
+	if s.Config.CacheDir != "" {
+		return s.Config.CacheDir
+	}
+	if dir := os.Getenv("XDG_CACHE_HOME"); dir != "" {
+		return filepath.Join(dir, "todo")
+	}
+	return filepath.Join(s.Config.Home, ".cache", "todo")
+}
+
+// Returns the tasks of a storage backend along with their tag indexes.
+func loadIndex(store todotxt.Store) (*todotxt.Index, error) {
+	if indexer, ok := store.(todotxt.Indexer); ok {
+		index, err := indexer.Index()
+		return index, storeError(store, err)
+	}
+	tasks, err := loadTasks(store)
+	if err != nil {
+		return nil, err
+	}
+	return todotxt.NewIndex(tasks), nil
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2014, Mauro Toffanin. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/toffanin/go-todo/library/v1"
+	"github.com/toffanin/go-todo/utils"
+)
+
+// load all the tasks from a storage backend
+func loadTasks(store todotxt.Store) (todotxt.TaskList, error) {
+	tasks, err := store.Load()
+	return tasks, storeError(store, err)
+}
+
+// replace the content of a storage backend with the given tasks
+func saveTasks(store todotxt.Store, tasks todotxt.TaskList) error {
+	return storeError(store, store.Save(tasks))
+}
+
+// Returns the error of a storage backend as an I/O error, with a hint if the
+// todo.txt file isn't accessible.
+func storeError(store todotxt.Store, err error) error {
+	if err == nil {
+		return nil
+	}
+	hint := ""
+	if fs, ok := store.(*todotxt.FileStore); ok {
+		switch {
+		case os.IsPermission(err):
+			hint = fmt.Sprintf("Please fix the permission bits of %s or %s.", fs.Path, path.Dir(fs.Path))
+		case isLocked(err):
+			hint = fmt.Sprintf("Please remove %s.lock if no other todo command is running.", fs.Path)
+		}
+	}
+	return utils.WrapError(utils.ErrIO, err, hint)
+}
+
+// Reports whether err is caused by a store locked by another process.
+func isLocked(err error) bool {
+	if e, ok := err.(*os.PathError); ok {
+		err = e.Err
+	}
+	return err == todotxt.ErrLocked
+}
+
+// Returns the numbers of the given tasks, separated by commas.
+func taskNumbers(tasks []*todotxt.Task) string {
+	nums := make([]string, len(tasks))
+	for i, task := range tasks {
+		nums[i] = strconv.FormatUint(task.Id, 10)
+	}
+	return strings.Join(nums, ", ")
+}
+
+// Returns a cycle of dependencies as the path of its task numbers (ex.:
+// "3 -> 5 -> 3").
+func cyclePath(cycle []*todotxt.Task) string {
+	path := strings.Replace(taskNumbers(cycle), ", ", " -> ", -1)
+	return fmt.Sprintf("%s -> %d", path, cycle[0].Id)
+}
+
+// Looks up a task by its number or by its stable identifier (id:/uuid: tags).
+func findTask(tasks todotxt.TaskList, ref string) (*todotxt.Task, error) {
+	task, err := tasks.Find(ref)
+	if err == todotxt.ErrTaskNotFound {
+		return nil, utils.NewError(utils.ErrFailure, "", "No task %s.", ref)
+	}
+	return task, err
+}
+
+// Returns the add-on tag used for new stable identifiers (TODOTXT_ID_TAG).
+func (s *Session) idTag() string {
+	if s.Config.IdTag == todotxt.UuidTag {
+		return todotxt.UuidTag
+	}
+	return todotxt.IdTag
+}
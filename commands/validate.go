@@ -0,0 +1,308 @@
+// Copyright (c) 2014, Mauro Toffanin. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/toffanin/go-todo/library/v1"
+	"github.com/toffanin/go-todo/utils"
+)
+
+var (
+	// anything between parentheses which could be meant as a priority
+	looksLikePriority = regexp.MustCompile(`^\([A-Za-z0-9]*\)$`)
+
+	// add-on tags holding a date
+	dateTags = []string{todotxt.DueTag, todotxt.ThresholdTag}
+)
+
+// A taskValidator checks and normalizes the tasks added to a task list.
+//
+// Every task goes through the steps of the pipeline in order: each step can
+// change the words of the task, reject the task with an error or report a
+// warning. Warnings are errors when strict is true.
+type taskValidator struct {
+	index     *todotxt.Index      // tasks already in the list
+	dates     *todotxt.DateParser // parser of the date expressions (ex.: due:friday)
+	dateOnAdd bool                // adds the creation date to the tasks without one
+	strict    bool                // turns the warnings into errors
+	now       time.Time           // date of the new tasks
+	warnings  []string
+}
+
+// A pendingTask is a task going through the validation pipeline.
+type pendingTask struct {
+	words []string
+	text  int           // index of the first word of the text
+	task  *todotxt.Task // parsed task, set by the parse step
+}
+
+// validationSteps is the validation pipeline.
+var validationSteps = []func(v *taskValidator, t *pendingTask) error{
+	(*taskValidator).checkPriority,
+	(*taskValidator).checkCreatedDate,
+	(*taskValidator).checkDateTags,
+	(*taskValidator).parse,
+	(*taskValidator).checkDescription,
+	(*taskValidator).checkRecurrence,
+	(*taskValidator).checkTags,
+	(*taskValidator).checkDependencies,
+}
+
+// newTaskValidator returns a validator of the tasks added to index.
+func (s *Session) newTaskValidator(index *todotxt.Index, strict bool) (*taskValidator, error) {
+	now := time.Now()
+	dates, err := s.dateParser(now)
+	if err != nil {
+		return nil, err
+	}
+	return &taskValidator{index: index, dates: dates, dateOnAdd: s.Config.DateOnAdd, strict: strict, now: now}, nil
+}
+
+// validate runs raw through the validation pipeline, returning the task to
+// add. The warnings found are collected inside the validator.
+func (v *taskValidator) validate(raw string) (*todotxt.Task, error) {
+	t := &pendingTask{words: strings.Fields(raw)}
+
+	// the completion mark and date aren't checked: they aren't added by hand
+	if len(t.words) > 0 && t.words[0] == "x" {
+		t.text = 1
+		if len(t.words) > 1 && looksLikeDate.MatchString(t.words[1]) {
+			t.text = 2
+		}
+	}
+
+	for _, step := range validationSteps {
+		if err := step(v, t); err != nil {
+			return nil, err
+		}
+	}
+	return t.task, nil
+}
+
+// learn adds the tags of a validated task to the index, so that the next
+// tasks can use them without warnings.
+func (v *taskValidator) learn(task *todotxt.Task) {
+	for _, project := range task.Projects {
+		v.index.Projects[project] = append(v.index.Projects[project], task.Id)
+	}
+	for _, context := range task.Contexts {
+		v.index.Contexts[context] = append(v.index.Contexts[context], task.Id)
+	}
+}
+
+// warn reports a warning, or fails in strict mode.
+func (v *taskValidator) warn(format string, args ...interface{}) error {
+	msg := fmt.Sprintf(format, args...)
+	if v.strict {
+		return utils.NewError(utils.ErrParse, "Remove the option --strict to add the task anyway.", "%s", msg)
+	}
+	v.warnings = append(v.warnings, msg)
+	return nil
+}
+
+// checkPriority normalizes lowercase priorities and rejects malformed ones.
+func (v *taskValidator) checkPriority(t *pendingTask) error {
+	if t.text >= len(t.words) || !looksLikePriority.MatchString(t.words[t.text]) {
+		return nil
+	}
+	word := t.words[t.text]
+	letter := strings.ToUpper(word[1 : len(word)-1])
+	if len(letter) != 1 || letter[0] < 'A' || letter[0] > 'Z' {
+		return utils.NewError(utils.ErrParse, "Priorities are a single letter between (A) and (Z).",
+			"Invalid priority %s.", word)
+	}
+	t.words[t.text] = "(" + letter + ")"
+	t.text++
+	return nil
+}
+
+// checkCreatedDate normalizes the creation date, rejecting invalid dates, and
+// adds the date of today when required.
+func (v *taskValidator) checkCreatedDate(t *pendingTask) error {
+	if t.text < len(t.words) && looksLikeDate.MatchString(t.words[t.text]) {
+		date, ok := normalizeDate(t.words[t.text])
+		if !ok {
+			return invalidDate(t.words[t.text])
+		}
+		t.words[t.text] = date
+		t.text++
+		return nil
+	}
+
+	// completed tasks keep their own dates
+	if v.dateOnAdd && (len(t.words) == 0 || t.words[0] != "x") {
+		date := v.now.Format(todotxt.DateLayout)
+		t.words = append(t.words[:t.text], append([]string{date}, t.words[t.text:]...)...)
+		t.text++
+	}
+	return nil
+}
+
+// checkDateTags converts the dates of the add-on tags into todo.txt format,
+// both the dates without leading zeros (ex.: due:2014-3-1) and the date
+// expressions (ex.: due:friday, t:+3d), rejecting invalid dates.
+func (v *taskValidator) checkDateTags(t *pendingTask) error {
+	for i := t.text; i < len(t.words); i++ {
+		for _, tag := range dateTags {
+			if !strings.HasPrefix(t.words[i], tag+":") {
+				continue
+			}
+			date, err := v.dates.Parse(strings.TrimPrefix(t.words[i], tag+":"))
+			if err != nil {
+				return invalidDate(t.words[i])
+			}
+			t.words[i] = tag + ":" + date.Format(todotxt.DateLayout)
+		}
+	}
+	return nil
+}
+
+// parse parses the normalized words as a task.
+func (v *taskValidator) parse(t *pendingTask) error {
+	task, err := todotxt.ParseTask(strings.Join(t.words, " "))
+	if err != nil {
+		return utils.WrapError(utils.ErrParse, err, "")
+	}
+	t.task = task
+	return nil
+}
+
+// checkDescription rejects the tasks without a description.
+func (v *taskValidator) checkDescription(t *pendingTask) error {
+	if t.text >= len(t.words) || t.task.Todo == "" {
+		return utils.NewError(utils.ErrParse, "Please describe the task.", "Empty task description.")
+	}
+	return nil
+}
+
+// checkRecurrence rejects the malformed recurrences (rec: tag).
+func (v *taskValidator) checkRecurrence(t *pendingTask) error {
+	if _, ok, err := t.task.Recurrence(); ok && err != nil {
+		value, _ := t.task.Tag(todotxt.RecurTag)
+		return utils.NewError(utils.ErrParse, recurrenceHint, "Invalid recurrence rec:%s.", value)
+	}
+	return nil
+}
+
+// checkTags warns about the projects and the contexts which aren't used by
+// any task of the list yet, suggesting the nearest known one.
+func (v *taskValidator) checkTags(t *pendingTask) error {
+	check := func(kind string, tags []string, known map[string][]uint64) error {
+		// without any tag there is nothing to compare with
+		if len(known) == 0 {
+			return nil
+		}
+		for _, tag := range tags {
+			if _, ok := known[tag]; ok {
+				continue
+			}
+			msg := fmt.Sprintf("Unknown %s %s", kind, tag)
+			if nearest := nearestTag(tag, known); nearest != "" {
+				msg += fmt.Sprintf(" (did you mean %s?)", nearest)
+			}
+			if err := v.warn("%s.", msg); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := check("project", t.task.Projects, v.index.Projects); err != nil {
+		return err
+	}
+	return check("context", t.task.Contexts, v.index.Contexts)
+}
+
+// checkDependencies warns about the dependencies (dep: tag) which don't
+// match the stable identifier of any task of the list.
+func (v *taskValidator) checkDependencies(t *pendingTask) error {
+	for _, id := range t.task.DependencyIds() {
+		known := false
+		for i := range v.index.Tasks {
+			if v.index.Tasks[i].StableId() == id {
+				known = true
+				break
+			}
+		}
+		if !known {
+			if err := v.warn("Unknown dependency dep:%s.", id); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// normalizeDate returns a date in todo.txt format (YYYY-MM-DD), accepting
+// months and days without leading zeros. It returns false if value isn't a
+// valid date.
+func normalizeDate(value string) (string, bool) {
+	date, err := time.Parse("2006-1-2", value)
+	if err != nil || !looksLikeDate.MatchString(value) {
+		return "", false
+	}
+	return date.Format(todotxt.DateLayout), true
+}
+
+// invalidDate returns the error reported for a word holding an invalid date.
+func invalidDate(word string) error {
+	return utils.NewError(utils.ErrParse,
+		"Dates are written as YYYY-MM-DD, or as expressions like friday, +3d or eom (see 'todo help add').",
+		"Invalid date %s.", word)
+}
+
+// nearestTag returns the known tag nearest to tag, or an empty string if no
+// known tag is near enough to be a typo.
+func nearestTag(tag string, known map[string][]uint64) string {
+	nearest, best := "", 0
+	for _, candidate := range todotxt.SortedKeys(known) {
+		d := editDistance(strings.ToLower(tag), strings.ToLower(candidate))
+		if nearest == "" || d < best {
+			nearest, best = candidate, d
+		}
+	}
+
+	// allow one typo every four characters, at least one
+	if limit := len([]rune(tag))/4 + 1; best > limit {
+		return ""
+	}
+	return nearest
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur := make([]int, len(rb)+1)
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(rb)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
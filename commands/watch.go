@@ -0,0 +1,58 @@
+// Copyright (c) 2014, Mauro Toffanin. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"fmt"
+	"time"
+)
+
+// watchDelay is the quiet period awaited after a change of a task file before
+// refreshing a listing, so that bursts of writes trigger a single refresh.
+const watchDelay = 250 * time.Millisecond
+
+// Renders a listing, then renders it again whenever TODO_FILE or DONE_FILE
+// change. It returns only if the listing can't be rendered.
+func (s *Session) watchListing(render func() error) error {
+	done := make(chan struct{})
+	defer close(done)
+
+	changes := make(chan struct{}, 1)
+	for _, setting := range []string{"TODO_FILE", "DONE_FILE"} {
+		events, err := s.store(setting).Watch(done)
+		if err != nil {
+			// the listing doesn't make sense without todo.txt
+			if setting == "TODO_FILE" {
+				return storeError(s.store(setting), err)
+			}
+			continue
+		}
+		go func() {
+			for range events {
+				select {
+				case changes <- struct{}{}:
+				default:
+				}
+			}
+		}()
+	}
+
+	for {
+		// clear the screen and move the cursor to the top-left corner
+		fmt.Fprint(s.IO.Out, "\033[H\033[2J")
+		if err := render(); err != nil {
+			return err
+		}
+
+		<-changes
+		for quiet := false; !quiet; {
+			select {
+			case <-changes:
+			case <-time.After(watchDelay):
+				quiet = true
+			}
+		}
+	}
+}
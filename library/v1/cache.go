@@ -0,0 +1,184 @@
+// Copyright (c) 2014, Mauro Toffanin. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package todotxt
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/gob"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// An Index holds a parsed task list along with the indexes of its tags.
+// Every index maps a tag to the numbers of the tasks which have it.
+type Index struct {
+	Tasks    TaskList
+	Projects map[string][]uint64 // Projects (ex.: +cleaning)
+	Contexts map[string][]uint64 // Contexts (ex.: @grocery)
+	Tags     map[string][]uint64 // Add-on tag keys (ex.: due)
+}
+
+// NewIndex builds the tag indexes of tasks.
+func NewIndex(tasks TaskList) *Index {
+	index := &Index{
+		Tasks:    tasks,
+		Projects: map[string][]uint64{},
+		Contexts: map[string][]uint64{},
+		Tags:     map[string][]uint64{},
+	}
+	for _, task := range tasks {
+		for _, project := range task.Projects {
+			index.Projects[project] = append(index.Projects[project], task.Id)
+		}
+		for _, context := range task.Contexts {
+			index.Contexts[context] = append(index.Contexts[context], task.Id)
+		}
+		for key := range task.AdditionalTags {
+			index.Tags[key] = append(index.Tags[key], task.Id)
+		}
+	}
+	return index
+}
+
+// SortedKeys returns the keys of a tag index in lexical order.
+func SortedKeys(tags map[string][]uint64) []string {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// An Indexer is a Store able to return the tag indexes of its tasks.
+type Indexer interface {
+	Index() (*Index, error)
+}
+
+// racyInterval is the interval within which a modification time is too
+// recent to be trusted: the file could still change without altering its
+// size or its modification time.
+const racyInterval = 2 * time.Second
+
+// A Cache keeps the parsed content of todo.txt files on disk, so that large
+// files don't need to be parsed again until they change.
+//
+// A cache entry is keyed by the size, the modification time and the SHA-1
+// hash of the file: it is used as is while size and modification time are
+// unchanged, and it is validated against the hash of the content otherwise.
+// Entries written by another version of the parser are ignored.
+type Cache struct {
+	Dir string // Directory holding the cache entries
+}
+
+// cacheVersion is the format version of the cache entries. It must be
+// incremented whenever the content of an Index changes, including the fields
+// of a Task derived by the parser, so that older entries are parsed again.
+const cacheVersion = 2
+
+// cacheEntry is the content of a cache entry.
+type cacheEntry struct {
+	Version int // Format version (see cacheVersion)
+	Size    int64
+	ModTime int64 // nanoseconds since the epoch
+	Hash    []byte
+	Index   *Index
+}
+
+// NewCache returns a Cache storing its entries inside dir.
+func NewCache(dir string) *Cache {
+	return &Cache{Dir: dir}
+}
+
+// Load returns the index of the todo.txt file at path, from the cache when
+// the file didn't change or by parsing the file otherwise.
+func (c *Cache) Load(path string) (*Index, error) {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return NewIndex(TaskList{}), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	entry := c.read(path)
+	if entry != nil && entry.Size == info.Size() && entry.ModTime == info.ModTime().UnixNano() &&
+		time.Since(info.ModTime()) > racyInterval {
+		return entry.Index, nil
+	}
+
+	// the file could have been changed, compare its content
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	hash := sha1.Sum(content)
+	if entry == nil || !bytes.Equal(entry.Hash, hash[:]) {
+		tasks, err := NewReader(bytes.NewReader(content)).ReadAll()
+		if err != nil {
+			return nil, err
+		}
+		entry = &cacheEntry{Version: cacheVersion, Hash: hash[:], Index: NewIndex(tasks)}
+	}
+	entry.Size = info.Size()
+	entry.ModTime = info.ModTime().UnixNano()
+
+	// a cache that can't be written only slows down the next call
+	c.write(path, entry)
+	return entry.Index, nil
+}
+
+// entryPath returns the path of the cache entry of the file at path.
+func (c *Cache) entryPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	hash := sha1.Sum([]byte(path))
+	return filepath.Join(c.Dir, hex.EncodeToString(hash[:])+".gob")
+}
+
+// read returns the cache entry of the file at path, or nil if there is no
+// valid entry. Entries written in another format version aren't valid.
+func (c *Cache) read(path string) *cacheEntry {
+	file, err := os.Open(c.entryPath(path))
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	entry := &cacheEntry{}
+	if err := gob.NewDecoder(file).Decode(entry); err != nil || entry.Index == nil ||
+		entry.Version != cacheVersion {
+		return nil
+	}
+	return entry
+}
+
+// write stores the cache entry of the file at path.
+func (c *Cache) write(path string, entry *cacheEntry) error {
+	if err := os.MkdirAll(c.Dir, 0700); err != nil {
+		return err
+	}
+
+	// write a temporary file first, so that readers never see partial entries
+	tmp, err := ioutil.TempFile(c.Dir, "entry")
+	if err != nil {
+		return err
+	}
+	err = gob.NewEncoder(tmp).Encode(entry)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), c.entryPath(path))
+}
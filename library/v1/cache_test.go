@@ -0,0 +1,61 @@
+// Copyright (c) 2014, Mauro Toffanin. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package todotxt
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCacheVersion(t *testing.T) {
+	dir, err := ioutil.TempDir("", "todotxt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// the file must be older than racyInterval for its entry to be trusted
+	path := filepath.Join(dir, "todo.txt")
+	if err := ioutil.WriteFile(path, []byte("Call mom\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	past := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(path, past, past); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		version int
+		want    []string
+	}{
+		{cacheVersion, []string{"Cached task"}},
+		{cacheVersion - 1, []string{"Call mom"}},
+		{cacheVersion + 1, []string{"Call mom"}},
+	}
+	cache := NewCache(filepath.Join(dir, "cache"))
+	for _, test := range tests {
+		// an entry matching the file, whose content tells where it comes from
+		entry := &cacheEntry{Version: test.version, Size: info.Size(), ModTime: info.ModTime().UnixNano(),
+			Index: NewIndex(parseTasks(t, "Cached task"))}
+		if err := cache.write(path, entry); err != nil {
+			t.Fatal(err)
+		}
+		index, err := cache.Load(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if lines := taskLines(index.Tasks); !reflect.DeepEqual(lines, test.want) {
+			t.Errorf("version %d: Load = %q, want %q", test.version, lines, test.want)
+		}
+	}
+}
@@ -0,0 +1,405 @@
+// Copyright (c) 2014, Mauro Toffanin. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package todotxt
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidDate is returned when a date expression can't be parsed.
+var ErrInvalidDate = errors.New("todotxt: invalid date expression")
+
+// maxDateUnits is the largest number of units of a date offset or of a
+// recurrence; larger numbers are typing mistakes.
+const maxDateUnits = 10000
+
+// A DateLocale holds the words of a language used by the date expressions.
+// All the words are lowercase.
+type DateLocale struct {
+	Days      [7][]string  // Names of the week days from Sunday, with abbreviations
+	Months    [12][]string // Names of the months from January, with abbreviations
+	Today     []string
+	Tomorrow  []string
+	Yesterday []string
+	Next      []string // Words marking the next week, month, day... (ex.: next-monday)
+	Week      []string
+	Month     []string
+	Year      []string
+	WeekStart time.Weekday // First day of the week where the language is spoken
+}
+
+// DateLocales holds the built-in locales, by language code.
+var DateLocales = map[string]*DateLocale{
+	"en": {
+		Days: [7][]string{
+			{"sunday", "sun"}, {"monday", "mon"}, {"tuesday", "tue", "tues"},
+			{"wednesday", "wed"}, {"thursday", "thu", "thur", "thurs"},
+			{"friday", "fri"}, {"saturday", "sat"},
+		},
+		Months: [12][]string{
+			{"january", "jan"}, {"february", "feb"}, {"march", "mar"},
+			{"april", "apr"}, {"may"}, {"june", "jun"}, {"july", "jul"},
+			{"august", "aug"}, {"september", "sep", "sept"},
+			{"october", "oct"}, {"november", "nov"}, {"december", "dec"},
+		},
+		Today:     []string{"today"},
+		Tomorrow:  []string{"tomorrow", "tmr"},
+		Yesterday: []string{"yesterday"},
+		Next:      []string{"next"},
+		Week:      []string{"week"},
+		Month:     []string{"month"},
+		Year:      []string{"year"},
+		WeekStart: time.Sunday,
+	},
+	"de": {
+		Days: [7][]string{
+			{"sonntag", "so"}, {"montag", "mo"}, {"dienstag", "di"},
+			{"mittwoch", "mi"}, {"donnerstag", "do"}, {"freitag", "fr"},
+			{"samstag", "sa", "sonnabend"},
+		},
+		Months: [12][]string{
+			{"januar", "jan"}, {"februar", "feb"}, {"märz", "maerz", "mär", "mrz"},
+			{"april", "apr"}, {"mai"}, {"juni", "jun"}, {"juli", "jul"},
+			{"august", "aug"}, {"september", "sep", "sept"},
+			{"oktober", "okt"}, {"november", "nov"}, {"dezember", "dez"},
+		},
+		Today:     []string{"heute"},
+		Tomorrow:  []string{"morgen"},
+		Yesterday: []string{"gestern"},
+		Next:      []string{"nächste", "nächsten", "nächster", "nächstes", "naechste", "naechsten", "naechster", "naechstes"},
+		Week:      []string{"woche"},
+		Month:     []string{"monat"},
+		Year:      []string{"jahr"},
+		WeekStart: time.Monday,
+	},
+	"es": {
+		Days: [7][]string{
+			{"domingo", "dom"}, {"lunes", "lun"}, {"martes", "mar"},
+			{"miércoles", "miercoles", "mié", "mie"}, {"jueves", "jue"},
+			{"viernes", "vie"}, {"sábado", "sabado", "sáb", "sab"},
+		},
+		Months: [12][]string{
+			{"enero", "ene"}, {"febrero", "feb"}, {"marzo", "mar"},
+			{"abril", "abr"}, {"mayo", "may"}, {"junio", "jun"}, {"julio", "jul"},
+			{"agosto", "ago"}, {"septiembre", "setiembre", "sep", "sept"},
+			{"octubre", "oct"}, {"noviembre", "nov"}, {"diciembre", "dic"},
+		},
+		Today:     []string{"hoy"},
+		Tomorrow:  []string{"mañana", "manana"},
+		Yesterday: []string{"ayer"},
+		Next:      []string{"próximo", "próxima", "proximo", "proxima"},
+		Week:      []string{"semana"},
+		Month:     []string{"mes"},
+		Year:      []string{"año", "ano"},
+		WeekStart: time.Monday,
+	},
+	"fr": {
+		Days: [7][]string{
+			{"dimanche", "dim"}, {"lundi", "lun"}, {"mardi", "mar"},
+			{"mercredi", "mer"}, {"jeudi", "jeu"}, {"vendredi", "ven"},
+			{"samedi", "sam"},
+		},
+		Months: [12][]string{
+			{"janvier", "janv"}, {"février", "fevrier", "févr", "fevr"},
+			{"mars"}, {"avril", "avr"}, {"mai"}, {"juin"},
+			{"juillet", "juil"}, {"août", "aout"}, {"septembre", "sept"},
+			{"octobre", "oct"}, {"novembre", "nov"}, {"décembre", "decembre", "déc", "dec"},
+		},
+		Today:     []string{"aujourd'hui", "aujourdhui"},
+		Tomorrow:  []string{"demain"},
+		Yesterday: []string{"hier"},
+		Next:      []string{"prochain", "prochaine"},
+		Week:      []string{"semaine"},
+		Month:     []string{"mois"},
+		Year:      []string{"année", "annee", "an"},
+		WeekStart: time.Monday,
+	},
+	"it": {
+		Days: [7][]string{
+			{"domenica", "dom"}, {"lunedì", "lunedi", "lun"}, {"martedì", "martedi", "mar"},
+			{"mercoledì", "mercoledi", "mer"}, {"giovedì", "giovedi", "gio"},
+			{"venerdì", "venerdi", "ven"}, {"sabato", "sab"},
+		},
+		Months: [12][]string{
+			{"gennaio", "gen"}, {"febbraio", "feb"}, {"marzo", "mar"},
+			{"aprile", "apr"}, {"maggio", "mag"}, {"giugno", "giu"},
+			{"luglio", "lug"}, {"agosto", "ago"}, {"settembre", "set"},
+			{"ottobre", "ott"}, {"novembre", "nov"}, {"dicembre", "dic"},
+		},
+		Today:     []string{"oggi"},
+		Tomorrow:  []string{"domani"},
+		Yesterday: []string{"ieri"},
+		Next:      []string{"prossimo", "prossima"},
+		Week:      []string{"settimana"},
+		Month:     []string{"mese"},
+		Year:      []string{"anno"},
+		WeekStart: time.Monday,
+	},
+}
+
+// LookupDateLocale returns the built-in locale of a language, given either
+// as a language code (ex.: "de") or as a POSIX locale (ex.: "de_DE.UTF-8").
+func LookupDateLocale(name string) (*DateLocale, bool) {
+	name = strings.ToLower(name)
+	if i := strings.IndexAny(name, "_-.@"); i >= 0 {
+		name = name[:i]
+	}
+	locale, ok := DateLocales[name]
+	return locale, ok
+}
+
+var (
+	// dates in todo.txt format, with optional leading zeros
+	isoDate = regexp.MustCompile(`^\d{4}-\d{1,2}-\d{1,2}$`)
+
+	// offsets from today (ex.: +3d, -1w, 2b)
+	dateOffset = regexp.MustCompile(`^([+-]\d+)([dwmyb]?)$|^(\d+)([dwmyb])$`)
+)
+
+// A DateParser converts date expressions into dates. The expressions are
+// relative to Now and case insensitive; the English words are understood
+// whatever the locale:
+//
+//	2014-03-07, 2014-3-7   absolute dates
+//	today, tomorrow        (and yesterday) the obvious ones
+//	+3d, -1w, +2m, +1y     offsets in days, weeks, months and years
+//	+5b                    offset in business days (Monday to Friday)
+//	friday, fri            the next Friday, a week from today on Fridays
+//	next-monday            the Monday of the next week
+//	next-week              the first day of the next week (and month, year)
+//	mar-7, 7-march         the next 7th of March, today included
+//	eow, eom, eoy          the last day of the week, the month or the year
+//
+// The weeks start on WeekStart.
+type DateParser struct {
+	Now       time.Time    // Reference date of the expressions
+	Locale    *DateLocale  // Language of the expressions
+	WeekStart time.Weekday // First day of the week
+}
+
+// NewDateParser returns a DateParser of English expressions relative to now.
+func NewDateParser(now time.Time) *DateParser {
+	en := DateLocales["en"]
+	return &DateParser{Now: now, Locale: en, WeekStart: en.WeekStart}
+}
+
+// Parse returns the date of an expression, at midnight in the location of
+// Now. It returns ErrInvalidDate if the expression can't be parsed.
+func (p *DateParser) Parse(expr string) (time.Time, error) {
+	expr = strings.ToLower(strings.TrimSpace(expr))
+	today := p.today()
+
+	if isoDate.MatchString(expr) {
+		date, err := time.ParseInLocation("2006-1-2", expr, today.Location())
+		if err != nil {
+			return time.Time{}, ErrInvalidDate
+		}
+		return date, nil
+	}
+	if m := dateOffset.FindStringSubmatch(expr); m != nil {
+		num, unit := m[1]+m[3], m[2]+m[4]
+		n, err := strconv.Atoi(strings.TrimPrefix(num, "+"))
+		if err != nil || n > maxDateUnits || n < -maxDateUnits {
+			return time.Time{}, ErrInvalidDate
+		}
+		return AddDateUnits(today, n, unit), nil
+	}
+
+	switch expr {
+	case "eow":
+		return p.weekStart(today).AddDate(0, 0, 6), nil
+	case "eom":
+		return time.Date(today.Year(), today.Month()+1, 0, 0, 0, 0, 0, today.Location()), nil
+	case "eoy":
+		return time.Date(today.Year(), time.December, 31, 0, 0, 0, 0, today.Location()), nil
+	}
+
+	for _, locale := range p.locales() {
+		if date, ok := p.parseWords(locale, expr, today); ok {
+			return date, nil
+		}
+	}
+	return time.Time{}, ErrInvalidDate
+}
+
+// parseWords parses the expressions made of the words of a locale.
+func (p *DateParser) parseWords(l *DateLocale, expr string, today time.Time) (time.Time, bool) {
+	switch {
+	case matchWord(expr, l.Today):
+		return today, true
+	case matchWord(expr, l.Tomorrow):
+		return today.AddDate(0, 0, 1), true
+	case matchWord(expr, l.Yesterday):
+		return today.AddDate(0, 0, -1), true
+	}
+	if day, ok := l.Weekday(expr); ok {
+		ahead := (int(day) - int(today.Weekday()) + 7) % 7
+		if ahead == 0 {
+			ahead = 7
+		}
+		return today.AddDate(0, 0, ahead), true
+	}
+
+	words := strings.Split(expr, "-")
+	if len(words) != 2 {
+		return time.Time{}, false
+	}
+
+	// next-monday, or monday-next as in most languages but English
+	for _, pair := range [][2]string{{words[0], words[1]}, {words[1], words[0]}} {
+		if !matchWord(pair[0], l.Next) {
+			continue
+		}
+		nextWeek := p.weekStart(today).AddDate(0, 0, 7)
+		switch what := pair[1]; {
+		case matchWord(what, l.Week):
+			return nextWeek, true
+		case matchWord(what, l.Month):
+			return time.Date(today.Year(), today.Month()+1, 1, 0, 0, 0, 0, today.Location()), true
+		case matchWord(what, l.Year):
+			return time.Date(today.Year()+1, time.January, 1, 0, 0, 0, 0, today.Location()), true
+		}
+		if day, ok := l.Weekday(pair[1]); ok {
+			return nextWeek.AddDate(0, 0, (int(day)-int(p.WeekStart)+7)%7), true
+		}
+	}
+
+	// mar-7, or 7-mar
+	for _, pair := range [][2]string{{words[0], words[1]}, {words[1], words[0]}} {
+		month, ok := l.month(pair[0])
+		if !ok {
+			continue
+		}
+		day, err := strconv.Atoi(pair[1])
+		// 2000 is a leap year: its months have the most days
+		if err != nil || day < 1 || day > daysIn(month, 2000) {
+			return time.Time{}, false
+		}
+		for year := today.Year(); ; year++ {
+			if day > daysIn(month, year) {
+				continue // the 29th of February of a leap year
+			}
+			if date := time.Date(year, month, day, 0, 0, 0, 0, today.Location()); !date.Before(today) {
+				return date, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// locales returns the locales understood by the parser, in order.
+func (p *DateParser) locales() []*DateLocale {
+	en := DateLocales["en"]
+	if p.Locale == nil || p.Locale == en {
+		return []*DateLocale{en}
+	}
+	return []*DateLocale{p.Locale, en}
+}
+
+// today returns the date of Now at midnight.
+func (p *DateParser) today() time.Time {
+	now := p.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+}
+
+// weekStart returns the first day of the week of date.
+func (p *DateParser) weekStart(date time.Time) time.Time {
+	return date.AddDate(0, 0, -((int(date.Weekday()) - int(p.WeekStart) + 7) % 7))
+}
+
+// Weekday returns the day of the week named word (lowercase).
+func (l *DateLocale) Weekday(word string) (time.Weekday, bool) {
+	for day, names := range l.Days {
+		if matchWord(word, names) {
+			return time.Weekday(day), true
+		}
+	}
+	return 0, false
+}
+
+// month returns the month named word.
+func (l *DateLocale) month(word string) (time.Month, bool) {
+	for month, names := range l.Months {
+		if matchWord(word, names) {
+			return time.Month(month + 1), true
+		}
+	}
+	return 0, false
+}
+
+// matchWord returns true if word is one of words.
+func matchWord(word string, words []string) bool {
+	for _, w := range words {
+		if word == w {
+			return true
+		}
+	}
+	return false
+}
+
+// AddDateUnits adds n units to date, where unit is "d" (days, the default),
+// "w" (weeks), "m" (months), "y" (years) or "b" (business days, Monday to
+// Friday). Adding months and years keeps the day within the resulting month:
+// a month after the 31st of January is the last day of February.
+func AddDateUnits(date time.Time, n int, unit string) time.Time {
+	switch unit {
+	case "w":
+		return date.AddDate(0, 0, 7*n)
+	case "m":
+		return addMonths(date, n)
+	case "y":
+		return addMonths(date, 12*n)
+	case "b":
+		return addBusinessDays(date, n)
+	}
+	return date.AddDate(0, 0, n)
+}
+
+// addMonths adds n months to date, clamping the day to the last day of the
+// resulting month.
+func addMonths(date time.Time, n int) time.Time {
+	first := time.Date(date.Year(), date.Month()+time.Month(n), 1, 0, 0, 0, 0, date.Location())
+	day := date.Day()
+	if last := daysIn(first.Month(), first.Year()); day > last {
+		day = last
+	}
+	return time.Date(first.Year(), first.Month(), day, date.Hour(), date.Minute(), date.Second(),
+		date.Nanosecond(), date.Location())
+}
+
+// addBusinessDays adds n days to date, skipping Saturdays and Sundays.
+func addBusinessDays(date time.Time, n int) time.Time {
+	step := 1
+	if n < 0 {
+		step, n = -1, -n
+	}
+	if n == 0 {
+		return date
+	}
+
+	// the last 1 to 5 days are counted one by one, so that date lands on a
+	// business day; a week after a business day is 5 business days after it
+	rest := (n-1)%5 + 1
+	weeks := (n - rest) / 5
+	for n = rest; n > 0; {
+		date = date.AddDate(0, 0, step)
+		if day := date.Weekday(); day != time.Saturday && day != time.Sunday {
+			n--
+		}
+	}
+	return date.AddDate(0, 0, step*7*weeks)
+}
+
+// daysIn returns the number of days of a month.
+func daysIn(month time.Month, year int) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
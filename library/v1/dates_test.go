@@ -0,0 +1,144 @@
+// Copyright (c) 2014, Mauro Toffanin. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package todotxt
+
+import (
+	"testing"
+	"time"
+)
+
+// date parses a date in todo.txt format, panicking on error.
+func date(value string) time.Time {
+	d, err := time.Parse(DateLayout, value)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func TestDateParser(t *testing.T) {
+	now := date("2014-06-04").Add(15 * time.Hour) // a Wednesday afternoon
+	tests := []struct {
+		locale    string
+		weekStart time.Weekday
+		expr      string
+		want      string
+	}{
+		{"en", time.Sunday, "2014-03-07", "2014-03-07"},
+		{"en", time.Sunday, "2014-3-7", "2014-03-07"},
+		{"en", time.Sunday, "Today", "2014-06-04"},
+		{"en", time.Sunday, "tomorrow", "2014-06-05"},
+		{"en", time.Sunday, "tmr", "2014-06-05"},
+		{"en", time.Sunday, "yesterday", "2014-06-03"},
+		{"en", time.Sunday, "+3d", "2014-06-07"},
+		{"en", time.Sunday, "+3", "2014-06-07"},
+		{"en", time.Sunday, "3d", "2014-06-07"},
+		{"en", time.Sunday, "-1w", "2014-05-28"},
+		{"en", time.Sunday, "+2m", "2014-08-04"},
+		{"en", time.Sunday, "+1y", "2015-06-04"},
+		{"en", time.Sunday, "+2b", "2014-06-06"},
+		{"en", time.Sunday, "+3b", "2014-06-09"},
+		{"en", time.Sunday, "-3b", "2014-05-30"},
+		{"en", time.Sunday, "friday", "2014-06-06"},
+		{"en", time.Sunday, "fri", "2014-06-06"},
+		{"en", time.Sunday, "wednesday", "2014-06-11"},
+		{"en", time.Sunday, "tuesday", "2014-06-10"},
+		{"en", time.Sunday, "next-monday", "2014-06-09"},
+		{"en", time.Sunday, "next-sunday", "2014-06-08"},
+		{"en", time.Sunday, "next-week", "2014-06-08"},
+		{"en", time.Sunday, "next-month", "2014-07-01"},
+		{"en", time.Sunday, "next-year", "2015-01-01"},
+		{"en", time.Sunday, "mar-7", "2015-03-07"},
+		{"en", time.Sunday, "7-march", "2015-03-07"},
+		{"en", time.Sunday, "jun-4", "2014-06-04"},
+		{"en", time.Sunday, "dec-25", "2014-12-25"},
+		{"en", time.Sunday, "feb-29", "2016-02-29"},
+		{"en", time.Sunday, "eow", "2014-06-07"},
+		{"en", time.Sunday, "eom", "2014-06-30"},
+		{"en", time.Sunday, "eoy", "2014-12-31"},
+		{"en", time.Monday, "eow", "2014-06-08"},
+		{"en", time.Monday, "next-week", "2014-06-09"},
+		{"en", time.Monday, "next-sunday", "2014-06-15"},
+		{"de", time.Monday, "morgen", "2014-06-05"},
+		{"de", time.Monday, "freitag", "2014-06-06"},
+		{"de", time.Monday, "nächste-woche", "2014-06-09"},
+		{"de", time.Monday, "montag-nächste", "2014-06-09"},
+		{"de", time.Monday, "7-märz", "2015-03-07"},
+		{"de", time.Monday, "friday", "2014-06-06"},
+		{"fr", time.Monday, "demain", "2014-06-05"},
+	}
+	for _, test := range tests {
+		p := NewDateParser(now)
+		p.Locale, p.WeekStart = DateLocales[test.locale], test.weekStart
+		got, err := p.Parse(test.expr)
+		if err != nil {
+			t.Errorf("%s: Parse(%s): %v", test.locale, test.expr, err)
+			continue
+		}
+		if got.Format(DateLayout) != test.want || got.Hour() != 0 {
+			t.Errorf("%s: Parse(%s) = %v, want %s", test.locale, test.expr, got, test.want)
+		}
+	}
+}
+
+func TestDateParserErrors(t *testing.T) {
+	p := NewDateParser(date("2014-06-04"))
+	for _, expr := range []string{"", "someday", "2014-02-30", "2014-13-01", "feb-30", "feb-0", "next-foo",
+		"+3x", "morgen", "+10001b", "-99999999999d"} {
+		if got, err := p.Parse(expr); err != ErrInvalidDate {
+			t.Errorf("Parse(%s) = %v, %v, want ErrInvalidDate", expr, got, err)
+		}
+	}
+}
+
+func TestLookupDateLocale(t *testing.T) {
+	tests := []struct {
+		name string
+		want string // language code, empty if unknown
+	}{
+		{"en", "en"},
+		{"de_DE.UTF-8", "de"},
+		{"fr_CA", "fr"},
+		{"IT", "it"},
+		{"C", ""},
+		{"pt_BR.UTF-8", ""},
+	}
+	for _, test := range tests {
+		locale, ok := LookupDateLocale(test.name)
+		if ok != (test.want != "") || ok && locale != DateLocales[test.want] {
+			t.Errorf("LookupDateLocale(%s) = %v, %v, want %s", test.name, locale, ok, test.want)
+		}
+	}
+}
+
+func TestAddDateUnits(t *testing.T) {
+	tests := []struct {
+		date string
+		n    int
+		unit string
+		want string
+	}{
+		{"2014-01-31", 1, "d", "2014-02-01"},
+		{"2014-01-31", 2, "w", "2014-02-14"},
+		{"2014-01-31", 1, "m", "2014-02-28"},
+		{"2012-01-31", 1, "m", "2012-02-29"},
+		{"2014-03-31", -1, "m", "2014-02-28"},
+		{"2012-02-29", 1, "y", "2013-02-28"},
+		{"2014-06-06", 1, "b", "2014-06-09"},
+		{"2014-06-09", -1, "b", "2014-06-06"},
+		{"2014-06-07", 1, "b", "2014-06-09"},
+		{"2014-06-07", 5, "b", "2014-06-13"},
+		{"2014-06-08", -6, "b", "2014-05-30"},
+		{"2014-06-06", 10000, "b", "2052-10-04"},
+		{"2014-06-09", -10000, "b", "1976-02-09"},
+	}
+	for _, test := range tests {
+		got := AddDateUnits(date(test.date), test.n, test.unit)
+		if got.Format(DateLayout) != test.want {
+			t.Errorf("AddDateUnits(%s, %d, %s) = %s, want %s", test.date, test.n, test.unit,
+				got.Format(DateLayout), test.want)
+		}
+	}
+}
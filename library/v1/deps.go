@@ -0,0 +1,132 @@
+// Copyright (c) 2014, Mauro Toffanin. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package todotxt
+
+import (
+	"strings"
+)
+
+// DepTag is the add-on tag listing the tasks a task depends on, through
+// their stable identifiers (ex.: dep:3f2a9c01, see Task.StableId). A task
+// can have several dep: tags, or list several identifiers separated by
+// commas (ex.: dep:3f2a9c01,b2e4d6f8).
+const DepTag = "dep"
+
+// DependencyIds returns the stable identifiers of the tasks the task depends
+// on, in order of appearance.
+func (t *Task) DependencyIds() []string {
+	ids := []string{}
+	for _, word := range strings.Fields(t.Todo) {
+		if !strings.HasPrefix(word, DepTag+":") {
+			continue
+		}
+		for _, id := range strings.Split(strings.TrimPrefix(word, DepTag+":"), ",") {
+			if id != "" && !hasWord(ids, id) {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
+
+// Dependencies returns the tasks of the list the task depends on. The
+// identifiers which don't match any task of the list (ex.: archived tasks)
+// are ignored, as well as the task's own identifier: the task may be a copy
+// of a task of the list.
+func (tasks TaskList) Dependencies(task *Task) []*Task {
+	deps := []*Task{}
+	self := task.StableId()
+	for _, id := range task.DependencyIds() {
+		if id == self {
+			continue
+		}
+		for i := range tasks {
+			if tasks[i].StableId() == id {
+				deps = append(deps, &tasks[i])
+				break
+			}
+		}
+	}
+	return deps
+}
+
+// Blockers returns the dependencies of the task which aren't completed yet.
+func (tasks TaskList) Blockers(task *Task) []*Task {
+	blockers := []*Task{}
+	for _, dep := range tasks.Dependencies(task) {
+		if !dep.Completed {
+			blockers = append(blockers, dep)
+		}
+	}
+	return blockers
+}
+
+// Blocked returns true if the task depends on tasks which aren't completed.
+func (tasks TaskList) Blocked(task *Task) bool {
+	return len(tasks.Blockers(task)) > 0
+}
+
+// Dependents returns the tasks of the list which depend on the task, except
+// the task itself.
+func (tasks TaskList) Dependents(task *Task) []*Task {
+	dependents := []*Task{}
+	id := task.StableId()
+	if id == "" {
+		return dependents
+	}
+	for i := range tasks {
+		if tasks[i].StableId() != id && hasWord(tasks[i].DependencyIds(), id) {
+			dependents = append(dependents, &tasks[i])
+		}
+	}
+	return dependents
+}
+
+// DependencyCycle returns the tasks of a cycle of dependencies of the list,
+// in order of dependency (each task depends on the next one, and the last
+// one on the first one), or nil if there is no cycle. The tasks of a cycle
+// can never be started.
+func (tasks TaskList) DependencyCycle() []*Task {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := map[*Task]int{}
+	path := []*Task{}
+
+	var visit func(t *Task) []*Task
+	visit = func(t *Task) []*Task {
+		state[t] = visiting
+		path = append(path, t)
+		for _, dep := range tasks.Dependencies(t) {
+			switch state[dep] {
+			case visiting:
+				// the cycle starts where dep entered the path
+				for i := range path {
+					if path[i] == dep {
+						return append([]*Task{}, path[i:]...)
+					}
+				}
+			case unvisited:
+				if cycle := visit(dep); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[t] = visited
+		return nil
+	}
+
+	for i := range tasks {
+		if state[&tasks[i]] == unvisited {
+			if cycle := visit(&tasks[i]); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2014, Mauro Toffanin. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package todotxt
+
+import (
+	"reflect"
+	"testing"
+)
+
+// taskIds returns the numbers of tasks.
+func taskIds(tasks []*Task) []uint64 {
+	ids := []uint64{}
+	for _, task := range tasks {
+		ids = append(ids, task.Id)
+	}
+	return ids
+}
+
+func TestDependencies(t *testing.T) {
+	tasks := parseTasks(t,
+		"Buy paint id:a1",
+		"x 2014-06-01 Buy brushes id:b2",
+		"Paint the fence id:c3 dep:a1,b2 dep:zz",
+		"Loop on itself id:d4 dep:d4",
+		"Self and paint id:e5 dep:e5 dep:a1",
+	)
+	tests := []struct {
+		task       int // index in tasks
+		deps       []uint64
+		blockers   []uint64
+		dependents []uint64
+	}{
+		{0, []uint64{}, []uint64{}, []uint64{3, 5}},
+		{1, []uint64{}, []uint64{}, []uint64{3}},
+		{2, []uint64{1, 2}, []uint64{1}, []uint64{}},
+		{3, []uint64{}, []uint64{}, []uint64{}},
+		{4, []uint64{1}, []uint64{1}, []uint64{}},
+	}
+	for _, test := range tests {
+		// a copy of the task must give the same results
+		task := tasks[test.task]
+		for _, which := range []*Task{&tasks[test.task], &task} {
+			deps, blockers := taskIds(tasks.Dependencies(which)), taskIds(tasks.Blockers(which))
+			dependents := taskIds(tasks.Dependents(which))
+			if !reflect.DeepEqual(deps, test.deps) || !reflect.DeepEqual(blockers, test.blockers) ||
+				!reflect.DeepEqual(dependents, test.dependents) {
+				t.Errorf("task %d: dependencies %v, blockers %v, dependents %v, want %v, %v, %v", task.Id,
+					deps, blockers, dependents, test.deps, test.blockers, test.dependents)
+			}
+			if tasks.Blocked(which) != (len(test.blockers) > 0) {
+				t.Errorf("task %d: Blocked() = %v", task.Id, tasks.Blocked(which))
+			}
+		}
+	}
+}
+
+func TestDependencyCycle(t *testing.T) {
+	tests := []struct {
+		tasks []string
+		cycle []uint64 // nil if there is no cycle
+	}{
+		{[]string{"A id:a", "B id:b dep:a", "C id:c dep:a,b"}, nil},
+		{[]string{"A id:a dep:a"}, nil},
+		{[]string{"A id:a dep:b", "B id:b dep:a"}, []uint64{1, 2}},
+		{[]string{"A id:a dep:b", "B id:b dep:c", "C id:c dep:a", "D id:d dep:a"}, []uint64{1, 2, 3}},
+		{[]string{"D id:d dep:b", "A id:a", "B id:b dep:c", "C id:c dep:b"}, []uint64{3, 4}},
+		{[]string{"x 2014-06-01 A id:a dep:b", "x 2014-06-01 B id:b dep:a"}, []uint64{1, 2}},
+		{[]string{"A id:a dep:missing", "B dep:a"}, nil},
+	}
+	for _, test := range tests {
+		tasks := parseTasks(t, test.tasks...)
+		cycle := tasks.DependencyCycle()
+		if test.cycle == nil && cycle != nil || test.cycle != nil && !reflect.DeepEqual(taskIds(cycle), test.cycle) {
+			t.Errorf("DependencyCycle(%q) = %v, want %v", test.tasks, taskIds(cycle), test.cycle)
+		}
+	}
+}
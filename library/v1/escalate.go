@@ -0,0 +1,95 @@
+// Copyright (c) 2014, Mauro Toffanin. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package todotxt
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ErrInvalidEscalationRule is returned for a malformed escalation rule.
+var ErrInvalidEscalationRule = errors.New("todotxt: invalid escalation rule")
+
+// escalationRule matches an escalation rule (ex.: overdue:A, 2d:B, today:C).
+var escalationRule = regexp.MustCompile(`^(overdue|today|(\d+)d):([A-Za-z])$`)
+
+// An EscalationRule raises the priority of the open tasks whose due date is
+// near: the tasks due within Days days (0 for the tasks due today, -1 for the
+// overdue ones) get at least Priority.
+type EscalationRule struct {
+	Days     int    // Maximum number of days until the due date
+	Priority string // Minimum priority, from A to Z
+}
+
+// String returns the rule in the syntax of ParseEscalationRules.
+func (r EscalationRule) String() string {
+	switch r.Days {
+	case -1:
+		return "overdue:" + r.Priority
+	case 0:
+		return "today:" + r.Priority
+	}
+	return strconv.Itoa(r.Days) + "d:" + r.Priority
+}
+
+// ParseEscalationRules parses a list of escalation rules separated by spaces
+// or commas. Every rule is written WHEN:PRIORITY, where WHEN is overdue,
+// today or Nd (due within N days). For example, "2d:B overdue:A" raises the
+// tasks due within two days to (B) and the overdue tasks to (A).
+func ParseEscalationRules(spec string) ([]EscalationRule, error) {
+	rules := []EscalationRule{}
+	separator := func(r rune) bool { return r == ',' || unicode.IsSpace(r) }
+	for _, field := range strings.FieldsFunc(spec, separator) {
+		m := escalationRule.FindStringSubmatch(field)
+		if m == nil {
+			return nil, ErrInvalidEscalationRule
+		}
+		r := EscalationRule{Priority: strings.ToUpper(m[3])}
+		switch m[1] {
+		case "overdue":
+			r.Days = -1
+		case "today":
+			r.Days = 0
+		default:
+			days, err := strconv.Atoi(m[2])
+			if err != nil {
+				return nil, ErrInvalidEscalationRule
+			}
+			r.Days = days
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// Escalate raises the priority of the task on the given day according to
+// the rules, and returns its previous priority ("" if none) along with true
+// if the priority changed. Completed tasks and tasks without a due date are
+// left untouched, and so are the tasks with a higher priority already.
+func (t *Task) Escalate(rules []EscalationRule, day time.Time) (string, bool) {
+	if t.Completed || t.DueDate.IsZero() {
+		return "", false
+	}
+	due := t.DueDate.Format(DateLayout)
+
+	priority := t.Priority
+	for _, r := range rules {
+		limit := day.AddDate(0, 0, r.Days).Format(DateLayout)
+		if due <= limit && (priority == "" || r.Priority < priority) {
+			priority = r.Priority
+		}
+	}
+	if priority == t.Priority {
+		return "", false
+	}
+	old := t.Priority
+	t.Priority = priority
+	t.Raw = t.String()
+	return old, true
+}
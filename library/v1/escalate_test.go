@@ -0,0 +1,81 @@
+// Copyright (c) 2014, Mauro Toffanin. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package todotxt
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseEscalationRules(t *testing.T) {
+	tests := []struct {
+		spec string
+		want []EscalationRule
+		err  error
+	}{
+		{"", []EscalationRule{}, nil},
+		{"overdue:A", []EscalationRule{{-1, "A"}}, nil},
+		{"2d:B overdue:A", []EscalationRule{{2, "B"}, {-1, "A"}}, nil},
+		{"today:c,7d:d", []EscalationRule{{0, "C"}, {7, "D"}}, nil},
+		{" 0d:B ,, 10d:Z ", []EscalationRule{{0, "B"}, {10, "Z"}}, nil},
+		{"overdue", nil, ErrInvalidEscalationRule},
+		{"tomorrow:A", nil, ErrInvalidEscalationRule},
+		{"2w:A", nil, ErrInvalidEscalationRule},
+		{"-1d:A", nil, ErrInvalidEscalationRule},
+		{"2d:AB", nil, ErrInvalidEscalationRule},
+		{"overdue:A 2d:1", nil, ErrInvalidEscalationRule},
+	}
+	for _, test := range tests {
+		got, err := ParseEscalationRules(test.spec)
+		if !reflect.DeepEqual(got, test.want) || err != test.err {
+			t.Errorf("ParseEscalationRules(%q) = %v, %v, want %v, %v", test.spec, got, err, test.want, test.err)
+		}
+	}
+
+	rules := []EscalationRule{{-1, "A"}, {0, "B"}, {3, "C"}}
+	for i, want := range []string{"overdue:A", "today:B", "3d:C"} {
+		if rules[i].String() != want {
+			t.Errorf("%#v.String() = %s, want %s", rules[i], rules[i], want)
+		}
+	}
+}
+
+func TestEscalate(t *testing.T) {
+	rules := []EscalationRule{{3, "C"}, {0, "B"}, {-1, "A"}}
+	today := date("2014-06-04")
+	tests := []struct {
+		task    string
+		want    string // task after the escalation
+		old     string
+		changed bool
+	}{
+		{"Pay rent due:2014-06-03", "(A) Pay rent due:2014-06-03", "", true},
+		{"(C) Pay rent due:2014-05-01", "(A) Pay rent due:2014-05-01", "C", true},
+		{"Call mom due:2014-06-04", "(B) Call mom due:2014-06-04", "", true},
+		{"(D) Call mom due:2014-06-04", "(B) Call mom due:2014-06-04", "D", true},
+		{"Report due:2014-06-07 +work", "(C) Report due:2014-06-07 +work", "", true},
+		{"Report due:2014-06-05", "(C) Report due:2014-06-05", "", true},
+		{"Report due:2014-06-08", "Report due:2014-06-08", "", false},
+		{"(A) Report due:2014-06-07", "(A) Report due:2014-06-07", "", false},
+		{"(B) Call mom due:2014-06-03", "(A) Call mom due:2014-06-03", "B", true},
+		{"(A) Call mom due:2014-06-03", "(A) Call mom due:2014-06-03", "", false},
+		{"x 2014-06-01 Pay rent due:2014-06-03", "x 2014-06-01 Pay rent due:2014-06-03", "", false},
+		{"Someday maybe", "Someday maybe", "", false},
+	}
+	for _, test := range tests {
+		task, _ := ParseTask(test.task)
+		old, changed := task.Escalate(rules, today)
+		if task.String() != test.want || task.Raw != test.want || old != test.old || changed != test.changed {
+			t.Errorf("Escalate(%s) = %s, %q, %v, want %s, %q, %v", test.task, task, old, changed, test.want,
+				test.old, test.changed)
+		}
+	}
+
+	// no rules, no escalation
+	task, _ := ParseTask("Pay rent due:2014-06-03")
+	if _, changed := task.Escalate(nil, today); changed || task.Priority != "" {
+		t.Errorf("Escalate(nil) = %s, %v", task, changed)
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright (c) 2014, Mauro Toffanin. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package todotxt
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Add-on tags holding the stable identifier of a task.
+const (
+	IdTag   = "id"   // short identifier (ex.: id:3f2a9c01)
+	UuidTag = "uuid" // RFC 4122 identifier (ex.: uuid:0b9e7d3c-...)
+)
+
+// ErrTaskNotFound is returned when a lookup doesn't match any task.
+var ErrTaskNotFound = errors.New("todotxt: task not found")
+
+// StableId returns the stable identifier of the task, taken from its id: or
+// uuid: add-on tag. It returns an empty string if the task has none.
+func (t *Task) StableId() string {
+	if id, ok := t.Tag(IdTag); ok {
+		return id
+	}
+	if id, ok := t.Tag(UuidTag); ok {
+		return id
+	}
+	return ""
+}
+
+// NewStableId generates a random identifier suitable for the add-on tag
+// named tag: a RFC 4122 version 4 UUID for uuid:, 8 hex digits otherwise.
+func NewStableId(tag string) (string, error) {
+	size := 4
+	if tag == UuidTag {
+		size = 16
+	}
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	if tag != UuidTag {
+		return hex.EncodeToString(b), nil
+	}
+
+	// set version (4) and variant (RFC 4122) bits
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// Find returns the task referenced by ref, which can be either a task number
+// (as assigned by ReadAll) or a stable identifier (see Task.StableId).
+// Stable identifiers can be prefixed with their tag name (ex.: id:3f2a9c01).
+func (tasks TaskList) Find(ref string) (*Task, error) {
+	if n, err := strconv.ParseUint(ref, 10, 64); err == nil {
+		for i := range tasks {
+			if tasks[i].Id == n {
+				return &tasks[i], nil
+			}
+		}
+	}
+
+	// strip the optional tag name
+	for _, tag := range []string{IdTag, UuidTag} {
+		ref = strings.TrimPrefix(ref, tag+":")
+	}
+	for i := range tasks {
+		if id := tasks[i].StableId(); id != "" && id == ref {
+			return &tasks[i], nil
+		}
+	}
+	return nil, ErrTaskNotFound
+}
+
+// AssignIds adds a new stable identifier to every task of the list that
+// doesn't have one yet, using the add-on tag named tag (IdTag or UuidTag).
+// It returns the number of tasks changed.
+func (tasks TaskList) AssignIds(tag string) (int, error) {
+	n := 0
+	for i := range tasks {
+		if tasks[i].StableId() != "" {
+			continue
+		}
+		id, err := tasks.uniqueId(tag)
+		if err != nil {
+			return n, err
+		}
+		tasks[i].SetTag(tag, id)
+		n++
+	}
+	return n, nil
+}
+
+// AssignId adds a new stable identifier to task, unique among tasks.
+// Tasks which already have a stable identifier are left untouched.
+func (tasks TaskList) AssignId(task *Task, tag string) error {
+	if task.StableId() != "" {
+		return nil
+	}
+	id, err := tasks.uniqueId(tag)
+	if err != nil {
+		return err
+	}
+	task.SetTag(tag, id)
+	return nil
+}
+
+// uniqueId generates a stable identifier not used by any task of the list.
+// Identifiers made only of digits are discarded, since they would be
+// mistaken for task numbers.
+func (tasks TaskList) uniqueId(tag string) (string, error) {
+	for {
+		id, err := NewStableId(tag)
+		if err != nil {
+			return "", err
+		}
+		if _, err := strconv.ParseUint(id, 10, 64); err == nil {
+			continue
+		}
+		used := false
+		for i := range tasks {
+			if tasks[i].StableId() == id {
+				used = true
+				break
+			}
+		}
+		if !used {
+			return id, nil
+		}
+	}
+}
@@ -0,0 +1,258 @@
+// Copyright (c) 2014, Mauro Toffanin. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package todotxt
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A Conflict describes a task that was changed in incompatible ways on both
+// sides of a three-way merge.
+type Conflict struct {
+	Index  int      // Position of the conflicting task inside the merged list
+	Fields []string // Fields changed on both sides (ex.: "priority", "tag:due")
+	Base   *Task    // Common ancestor; nil if the task was added on both sides
+	Ours   *Task    // Our version; nil if the task was deleted on our side
+	Theirs *Task    // Their version; nil if the task was deleted on their side
+}
+
+// Identity returns a key which identifies the task regardless of its state.
+//
+// The key is made of the words of the task text, without the completion mark,
+// the priority, the dates, the projects, the contexts and the add-on tags, so
+// that two versions of the same task share the same identity even when they
+// have been completed, re-prioritized or tagged differently.
+//
+// Tasks with a stable identifier (see Task.StableId) are identified by it
+// instead, so they keep their identity even when their text is edited.
+func (t *Task) Identity() string {
+	if id := t.StableId(); id != "" {
+		return "id:" + id
+	}
+
+	words := []string{}
+	for _, token := range strings.Fields(t.Todo) {
+		if len(token) > 1 && (token[0] == '@' || token[0] == '+') {
+			continue
+		}
+		if _, _, ok := splitTag(token); ok {
+			continue
+		}
+		words = append(words, token)
+	}
+	return strings.Join(words, " ")
+}
+
+// Merge performs a three-way merge of two task lists derived from base.
+//
+// Tasks are matched across the three lists by identity (see Task.Identity).
+// Changes to the completion state, the priority, the created date, projects,
+// contexts and add-on tags are merged field by field; only the fields changed
+// in different ways on both sides are reported as conflicts. In case of
+// conflict the merged list holds our version of the conflicting fields.
+//
+// The merged list preserves the order of ours; tasks added only on their side
+// are appended at the end.
+func Merge(base, ours, theirs TaskList) (TaskList, []Conflict) {
+	baseIndex, _ := indexTasks(base)
+	oursIndex, oursKeys := indexTasks(ours)
+	theirsIndex, theirsKeys := indexTasks(theirs)
+
+	merged := TaskList{}
+	conflicts := []Conflict{}
+
+	for _, key := range oursKeys {
+		o, b := oursIndex[key], baseIndex[key]
+		t, ok := theirsIndex[key]
+		switch {
+		case !ok && b == nil: // added on our side
+			merged = append(merged, *o)
+		case !ok: // deleted on their side
+			if o.String() != b.String() {
+				conflicts = append(conflicts, Conflict{len(merged), []string{"deleted"}, b, o, nil})
+				merged = append(merged, *o)
+			}
+		default:
+			task, fields := mergeTask(b, o, t)
+			if len(fields) > 0 {
+				conflicts = append(conflicts, Conflict{len(merged), fields, b, o, t})
+			}
+			merged = append(merged, task)
+		}
+	}
+
+	for _, key := range theirsKeys {
+		if _, ok := oursIndex[key]; ok {
+			continue
+		}
+		t, b := theirsIndex[key], baseIndex[key]
+		switch {
+		case b == nil: // added on their side
+			merged = append(merged, *t)
+		case t.String() != b.String(): // deleted on our side
+			conflicts = append(conflicts, Conflict{len(merged), []string{"deleted"}, b, nil, t})
+			merged = append(merged, *t)
+		}
+	}
+
+	for i := range merged {
+		merged[i].Id = uint64(i + 1)
+	}
+	return merged, conflicts
+}
+
+// indexTasks maps every task of the list to its identity. Duplicated
+// identities are told apart by their occurrence number.
+// It returns the map and the keys in the same order of the list.
+func indexTasks(tasks TaskList) (map[string]*Task, []string) {
+	index := make(map[string]*Task, len(tasks))
+	keys := make([]string, 0, len(tasks))
+	seen := map[string]int{}
+	for i := range tasks {
+		identity := tasks[i].Identity()
+		key := identity + "\x00" + strconv.Itoa(seen[identity])
+		seen[identity]++
+		index[key] = &tasks[i]
+		keys = append(keys, key)
+	}
+	return index, keys
+}
+
+// mergeTask merges the changes made to base on both sides into a copy of
+// ours. A nil base stands for a task added on both sides.
+// It returns the merged task and the list of the conflicting fields.
+func mergeTask(base, ours, theirs *Task) (Task, []string) {
+	if base == nil {
+		base = &Task{}
+	}
+	result := *ours
+	fields := []string{}
+
+	// completion mark and completion date
+	switch merge3(completion(base), completion(ours), completion(theirs)) {
+	case conflict:
+		fields = append(fields, "completion")
+	case useTheirs:
+		result.Completed = theirs.Completed
+		result.CompletedDate = theirs.CompletedDate
+	}
+
+	// priority
+	switch merge3(base.Priority, ours.Priority, theirs.Priority) {
+	case conflict:
+		fields = append(fields, "priority")
+	case useTheirs:
+		result.Priority = theirs.Priority
+	}
+
+	// created date
+	switch merge3(base.CreatedDate.String(), ours.CreatedDate.String(), theirs.CreatedDate.String()) {
+	case conflict:
+		fields = append(fields, "created")
+	case useTheirs:
+		result.CreatedDate = theirs.CreatedDate
+	}
+
+	// projects and contexts can't conflict: they are merged as sets
+	for _, project := range mergeSet(base.Projects, ours.Projects, theirs.Projects) {
+		if hasWord(theirs.Projects, project) {
+			result.AddProject(project)
+		} else {
+			result.RemoveProject(project)
+		}
+	}
+	for _, context := range mergeSet(base.Contexts, ours.Contexts, theirs.Contexts) {
+		if hasWord(theirs.Contexts, context) {
+			result.AddContext(context)
+		} else {
+			result.RemoveContext(context)
+		}
+	}
+
+	// add-on tags are merged key by key
+	keys := map[string]bool{}
+	for _, tags := range []map[string]string{base.AdditionalTags, ours.AdditionalTags, theirs.AdditionalTags} {
+		for key := range tags {
+			keys[key] = true
+		}
+	}
+	for _, key := range sortedKeys(keys) {
+		b, o, t := tagState(base, key), tagState(ours, key), tagState(theirs, key)
+		switch merge3(b, o, t) {
+		case conflict:
+			fields = append(fields, "tag:"+key)
+		case useTheirs:
+			if value, ok := theirs.Tag(key); ok {
+				result.SetTag(key, value)
+			} else {
+				result.RemoveTag(key)
+			}
+		}
+	}
+
+	return result, fields
+}
+
+// Outcomes of a three-way merge of a single field.
+const (
+	useOurs = iota
+	useTheirs
+	conflict
+)
+
+// merge3 decides which side wins for a single field.
+func merge3(base, ours, theirs string) int {
+	switch {
+	case ours == theirs, theirs == base:
+		return useOurs
+	case ours == base:
+		return useTheirs
+	}
+	return conflict
+}
+
+// mergeSet returns the words changed only on their side; ours already holds
+// every other change.
+func mergeSet(base, ours, theirs []string) []string {
+	changes := []string{}
+	for _, word := range append(append([]string{}, base...), theirs...) {
+		if hasWord(changes, word) {
+			continue
+		}
+		b, o, t := hasWord(base, word), hasWord(ours, word), hasWord(theirs, word)
+		if o != t && o == b {
+			changes = append(changes, word)
+		}
+	}
+	return changes
+}
+
+// completion encodes the completion state of a task as a string.
+func completion(t *Task) string {
+	if !t.Completed {
+		return ""
+	}
+	return "x " + t.CompletedDate.String()
+}
+
+// tagState encodes the presence and the value of an add-on tag as a string.
+func tagState(t *Task, key string) string {
+	if value, ok := t.Tag(key); ok {
+		return ":" + value
+	}
+	return ""
+}
+
+// sortedKeys returns the keys of a set in lexical order.
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
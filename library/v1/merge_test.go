@@ -0,0 +1,170 @@
+// Copyright (c) 2014, Mauro Toffanin. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package todotxt
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// parseTasks parses the lines of a todo.txt file, failing the test on error.
+func parseTasks(t *testing.T, lines ...string) TaskList {
+	tasks, err := NewReader(strings.NewReader(strings.Join(lines, "\n"))).ReadAll()
+	if err != nil {
+		t.Fatalf("ReadAll(%q): %v", lines, err)
+	}
+	return tasks
+}
+
+// taskLines returns the tasks of a list formatted as todo.txt lines.
+func taskLines(tasks TaskList) []string {
+	lines := []string{}
+	for i := range tasks {
+		lines = append(lines, tasks[i].String())
+	}
+	return lines
+}
+
+func TestIdentity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		same bool
+	}{
+		{"Call mom", "x 2014-06-01 (A) Call mom +family @phone due:2014-06-02", true},
+		{"2014-05-01 Call mom", "(B) Call mom", true},
+		{"Call mom", "Call dad", false},
+		{"Call mom id:1", "Call my mother id:1", true},
+		{"Call mom id:1", "Call mom id:2", false},
+	}
+	for _, test := range tests {
+		a, _ := ParseTask(test.a)
+		b, _ := ParseTask(test.b)
+		if same := a.Identity() == b.Identity(); same != test.same {
+			t.Errorf("Identity(%q) == Identity(%q) = %v, want %v", test.a, test.b, same, test.same)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name               string
+		base, ours, theirs []string
+		merged             []string
+		conflicts          [][]string // fields of every conflict
+	}{
+		{
+			name:   "unchanged",
+			base:   []string{"Call mom", "Buy milk"},
+			ours:   []string{"Call mom", "Buy milk"},
+			theirs: []string{"Call mom", "Buy milk"},
+			merged: []string{"Call mom", "Buy milk"},
+		},
+		{
+			name:   "changes on both sides",
+			base:   []string{"Call mom", "Buy milk"},
+			ours:   []string{"x 2014-06-01 Call mom", "Buy milk"},
+			theirs: []string{"(A) Call mom", "Buy milk @grocery"},
+			merged: []string{"x 2014-06-01 (A) Call mom", "Buy milk @grocery"},
+		},
+		{
+			name:      "priority conflict",
+			base:      []string{"(C) Call mom"},
+			ours:      []string{"(A) Call mom"},
+			theirs:    []string{"(B) Call mom"},
+			merged:    []string{"(A) Call mom"},
+			conflicts: [][]string{{"priority"}},
+		},
+		{
+			name:      "tag conflict",
+			base:      []string{"Pay rent due:2014-06-01"},
+			ours:      []string{"Pay rent due:2014-06-02"},
+			theirs:    []string{"Pay rent due:2014-06-03"},
+			merged:    []string{"Pay rent due:2014-06-02"},
+			conflicts: [][]string{{"tag:due"}},
+		},
+		{
+			name:   "tags merged key by key",
+			base:   []string{"Pay rent due:2014-06-01"},
+			ours:   []string{"Pay rent due:2014-06-01 t:2014-05-30"},
+			theirs: []string{"Pay rent due:2014-06-05"},
+			merged: []string{"Pay rent due:2014-06-05 t:2014-05-30"},
+		},
+		{
+			name:   "projects merged as sets",
+			base:   []string{"Report +work +q1"},
+			ours:   []string{"Report +work +q1 +urgent"},
+			theirs: []string{"Report +work"},
+			merged: []string{"Report +work +urgent"},
+		},
+		{
+			name:   "added on both sides",
+			base:   []string{"Call mom"},
+			ours:   []string{"Call mom", "Buy milk"},
+			theirs: []string{"Call mom", "Buy milk", "Walk dog"},
+			merged: []string{"Call mom", "Buy milk", "Walk dog"},
+		},
+		{
+			name:   "deleted on their side",
+			base:   []string{"Call mom", "Buy milk"},
+			ours:   []string{"Call mom", "Buy milk"},
+			theirs: []string{"Call mom"},
+			merged: []string{"Call mom"},
+		},
+		{
+			name:      "deleted on their side, changed on ours",
+			base:      []string{"Call mom", "Buy milk"},
+			ours:      []string{"Call mom", "(A) Buy milk"},
+			theirs:    []string{"Call mom"},
+			merged:    []string{"Call mom", "(A) Buy milk"},
+			conflicts: [][]string{{"deleted"}},
+		},
+		{
+			name:      "deleted on our side, changed on theirs",
+			base:      []string{"Call mom", "Buy milk"},
+			ours:      []string{"Call mom"},
+			theirs:    []string{"Call mom", "Buy milk @grocery"},
+			merged:    []string{"Call mom", "Buy milk @grocery"},
+			conflicts: [][]string{{"deleted"}},
+		},
+		{
+			name:   "stable identifiers",
+			base:   []string{"Call mom id:1"},
+			ours:   []string{"Call my mother id:1"},
+			theirs: []string{"(A) Call mom id:1"},
+			merged: []string{"(A) Call my mother id:1"},
+		},
+		{
+			name:   "duplicated tasks",
+			base:   []string{"Buy milk", "Buy milk"},
+			ours:   []string{"(A) Buy milk", "Buy milk"},
+			theirs: []string{"Buy milk", "(B) Buy milk"},
+			merged: []string{"(A) Buy milk", "(B) Buy milk"},
+		},
+	}
+
+	for _, test := range tests {
+		merged, conflicts := Merge(parseTasks(t, test.base...), parseTasks(t, test.ours...),
+			parseTasks(t, test.theirs...))
+		if lines := taskLines(merged); !reflect.DeepEqual(lines, test.merged) {
+			t.Errorf("%s: merged %q, want %q", test.name, lines, test.merged)
+		}
+		fields := [][]string{}
+		for _, c := range conflicts {
+			fields = append(fields, c.Fields)
+		}
+		if test.conflicts == nil {
+			test.conflicts = [][]string{}
+		}
+		if !reflect.DeepEqual(fields, test.conflicts) {
+			t.Errorf("%s: conflicts %q, want %q", test.name, fields, test.conflicts)
+		}
+		for i := range merged {
+			if merged[i].Id != uint64(i+1) {
+				t.Errorf("%s: task %d numbered %d", test.name, i+1, merged[i].Id)
+			}
+		}
+	}
+}
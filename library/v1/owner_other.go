@@ -0,0 +1,15 @@
+// Copyright (c) 2014, Mauro Toffanin. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build windows || plan9
+// +build windows plan9
+
+package todotxt
+
+import (
+	"os"
+)
+
+// copyOwner does nothing: files have no numeric owner on this platform.
+func copyOwner(file *os.File, info os.FileInfo) {}
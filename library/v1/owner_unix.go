@@ -0,0 +1,22 @@
+// Copyright (c) 2014, Mauro Toffanin. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package todotxt
+
+import (
+	"os"
+	"syscall"
+)
+
+// copyOwner gives file the owner and the group of the file described by
+// info, when the process is allowed to. Only the owner of a file or root can
+// change it, so failures are ignored.
+func copyOwner(file *os.File, info os.FileInfo) {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		file.Chown(int(stat.Uid), int(stat.Gid))
+	}
+}
@@ -16,10 +16,11 @@ import (
 	"os"
 	"strings"
 	"time"
-	//	"unicode"
-	"github.com/toffanin/go-todo/utils"
 )
 
+// DateLayout is the layout used by todo.txt for all the dates of a task.
+const DateLayout = "2006-01-02"
+
 // Task represents a todo.txt task entry
 type Task struct {
 	Id             uint64 // Internal task ID
@@ -31,8 +32,10 @@ type Task struct {
 	AdditionalTags map[string]string // Add-on tags will be available here.
 	CreatedDate    time.Time
 	DueDate        time.Time
+	ThresholdDate  time.Time // Date before which the task can't be started (t: tag)
 	CompletedDate  time.Time
 	Completed      bool
+	Hidden         bool // Task never shown in the listings (h:1 tag)
 	Padding        uint
 }
 
@@ -88,7 +91,9 @@ func (r *Reader) Read() (*TaskList, error) {
 	if err == io.EOF {
 		return &r.tasks, err
 	}
-	utils.Check(err)
+	if err != nil {
+		return &r.tasks, err
+	}
 
 	// Set the split function for a Scanner that returns each line of text,
 	// stripped of any trailing end-of-line marker
@@ -151,6 +156,12 @@ func (r *Reader) ReadAll() (TaskList, error) {
 	return r.tasks, nil
 }
 
+// ParseTask parses a single line of text as a todo.txt task.
+func ParseTask(raw string) (*Task, error) {
+	r := Reader{}
+	return r.parseRecord(strings.TrimSpace(raw), 0)
+}
+
 // parseRecord reads and parses a single todo.txt task from r.
 func (r *Reader) parseRecord(raw string, id uint64) (*Task, error) {
 
@@ -158,44 +169,58 @@ func (r *Reader) parseRecord(raw string, id uint64) (*Task, error) {
 	task := Task{}
 
 	task.Raw = raw
-	task.Todo = raw
 	task.Id = id
-
-	// TODO: check for data completed date
-
-	// TODO: check for priority
-
-	// TODO: check for created date
-
-	// TODO: check for contexts and projects
-	// Set the split function for a Scanner that returns each token inside the
-	// line of text previously scanned
-	scanner := bufio.NewScanner(strings.NewReader(raw))
-	scanner.Split(bufio.ScanWords)
-	for scanner.Scan() {
-		token := scanner.Text()
-
-		if strings.IndexRune(token, '@') == 0 {
-			task.Contexts = append(task.Contexts, token)
-		}
-		if strings.IndexRune(token, '+') == 0 {
-			task.Projects = append(task.Projects, token)
+	text := raw
+
+	// check for completion mark and completion date
+	if strings.HasPrefix(text, "x ") {
+		task.Completed = true
+		text = strings.TrimLeft(text[2:], " ")
+		if date, rest, ok := cutDate(text); ok {
+			task.CompletedDate = date
+			text = rest
 		}
 	}
-	if err := scanner.Err(); err != nil {
-		fmt.Fprintln(os.Stderr, "reading input:", err)
+
+	// check for priority
+	if len(text) > 3 && text[0] == '(' && text[2] == ')' && text[3] == ' ' &&
+		text[1] >= 'A' && text[1] <= 'Z' {
+		task.Priority = text[1:2]
+		text = strings.TrimLeft(text[4:], " ")
 	}
-	//fmt.Printf("task: %s (contexts: %d) (projects: %d)\n", raw, contexts, projects)
 
-	// TODO: check for additional tags
+	// check for created date
+	if date, rest, ok := cutDate(text); ok {
+		task.CreatedDate = date
+		text = rest
+	}
 
 	// trim any remaining white spaces
-	task.Todo = strings.TrimSpace(task.Todo)
+	task.Todo = strings.TrimSpace(text)
 	//fmt.Println("task: ", raw)
 
+	// check for contexts, projects and additional tags
+	task.parseTags()
+
 	return &task, err
 }
 
+// cutDate strips a leading date in todo.txt format (YYYY-MM-DD) from text.
+// It returns false if text doesn't start with a valid date.
+func cutDate(text string) (time.Time, string, bool) {
+	if len(text) < len(DateLayout) {
+		return time.Time{}, text, false
+	}
+	if len(text) > len(DateLayout) && text[len(DateLayout)] != ' ' {
+		return time.Time{}, text, false
+	}
+	date, err := time.Parse(DateLayout, text[:len(DateLayout)])
+	if err != nil {
+		return time.Time{}, text, false
+	}
+	return date, strings.TrimLeft(text[len(DateLayout):], " "), true
+}
+
 //
 func (r *Reader) Len() uint64 {
 	return r.length - 1
@@ -0,0 +1,128 @@
+// Copyright (c) 2014, Mauro Toffanin. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package todotxt
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// RecurTag is the add-on tag holding the recurrence of a task (ex.: rec:1w).
+const RecurTag = "rec"
+
+// ErrInvalidRecurrence is returned for a malformed rec: tag.
+var ErrInvalidRecurrence = errors.New("todotxt: invalid recurrence")
+
+// recurrence matches the value of a rec: tag (ex.: 1w, +1m, 2b).
+var recurrence = regexp.MustCompile(`^(\+?)(\d*)([dwmyb])$`)
+
+// A Recurrence is the interval between the occurrences of a recurring task,
+// as held by its rec: tag.
+//
+// The next occurrence of a task is due an interval after the completion of
+// the task, or an interval after its previous due date for strict
+// recurrences (written with a leading +, ex.: rec:+1m).
+type Recurrence struct {
+	N      int    // Number of units
+	Unit   string // d (days), w (weeks), m (months), y (years) or b (business days)
+	Strict bool   // Counts from the previous due date instead of the completion date
+}
+
+// ParseRecurrence parses the value of a rec: tag. The number of units is
+// optional and defaults to 1 (ex.: rec:w).
+func ParseRecurrence(value string) (Recurrence, error) {
+	m := recurrence.FindStringSubmatch(value)
+	if m == nil {
+		return Recurrence{}, ErrInvalidRecurrence
+	}
+	r := Recurrence{N: 1, Unit: m[3], Strict: m[1] == "+"}
+	if m[2] != "" {
+		n, err := strconv.Atoi(m[2])
+		if err != nil || n == 0 || n > maxDateUnits {
+			return Recurrence{}, ErrInvalidRecurrence
+		}
+		r.N = n
+	}
+	return r, nil
+}
+
+// String returns the recurrence formatted as the value of a rec: tag.
+func (r Recurrence) String() string {
+	s := strconv.Itoa(r.N) + r.Unit
+	if r.Strict {
+		s = "+" + s
+	}
+	return s
+}
+
+// Next returns the date one interval after date.
+func (r Recurrence) Next(date time.Time) time.Time {
+	return AddDateUnits(date, r.N, r.Unit)
+}
+
+// Recurrence returns the recurrence of the task, or false if the task has no
+// rec: tag. It returns ErrInvalidRecurrence if the tag is malformed.
+func (t *Task) Recurrence() (Recurrence, bool, error) {
+	value, ok := t.Tag(RecurTag)
+	if !ok {
+		return Recurrence{}, false, nil
+	}
+	r, err := ParseRecurrence(value)
+	return r, true, err
+}
+
+// Recur returns the next occurrence of a recurring task completed on date,
+// or nil if the task doesn't recur. The next occurrence is a copy of the
+// task, not completed, whose dates are advanced by the recurrence:
+//
+//   - the due date (due:) is an interval after date, or after the previous
+//     due date for strict recurrences; tasks without a due date get one;
+//   - the threshold date (t:) keeps its distance from the due date, or is
+//     advanced like a due date when the task has no due date;
+//   - the created date, if any, becomes date.
+//
+// Recur must be called before the task is marked as done.
+func (t *Task) Recur(date time.Time) (*Task, error) {
+	r, ok, err := t.Recurrence()
+	if !ok || err != nil {
+		return nil, err
+	}
+
+	next := *t
+	next.Id = 0
+	next.Reopen()
+	next.parseTags() // don't share the tags with t
+	if !next.CreatedDate.IsZero() {
+		next.CreatedDate = date
+	}
+
+	due, hasDue := t.dateTag(DueTag)
+	threshold, hasThreshold := t.dateTag(ThresholdTag)
+	base := func(previous time.Time) time.Time {
+		if r.Strict {
+			return r.Next(previous)
+		}
+		return r.Next(date)
+	}
+
+	switch {
+	case hasDue:
+		newDue := base(due)
+		next.SetTag(DueTag, newDue.Format(DateLayout))
+		if hasThreshold {
+			days := int(due.Sub(threshold).Hours() / 24)
+			next.SetTag(ThresholdTag, newDue.AddDate(0, 0, -days).Format(DateLayout))
+		}
+	case hasThreshold:
+		next.SetTag(ThresholdTag, base(threshold).Format(DateLayout))
+	default:
+		next.SetTag(DueTag, r.Next(date).Format(DateLayout))
+	}
+
+	next.Raw = next.String()
+	return &next, nil
+}
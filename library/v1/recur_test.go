@@ -0,0 +1,81 @@
+// Copyright (c) 2014, Mauro Toffanin. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package todotxt
+
+import (
+	"testing"
+)
+
+func TestParseRecurrence(t *testing.T) {
+	tests := []struct {
+		value string
+		want  Recurrence
+		err   error
+	}{
+		{"1w", Recurrence{1, "w", false}, nil},
+		{"w", Recurrence{1, "w", false}, nil},
+		{"+1m", Recurrence{1, "m", true}, nil},
+		{"10d", Recurrence{10, "d", false}, nil},
+		{"+10000b", Recurrence{10000, "b", true}, nil},
+		{"10001b", Recurrence{}, ErrInvalidRecurrence},
+		{"9999999999b", Recurrence{}, ErrInvalidRecurrence},
+		{"+2b", Recurrence{2, "b", true}, nil},
+		{"0d", Recurrence{}, ErrInvalidRecurrence},
+		{"1x", Recurrence{}, ErrInvalidRecurrence},
+		{"-1d", Recurrence{}, ErrInvalidRecurrence},
+		{"", Recurrence{}, ErrInvalidRecurrence},
+	}
+	for _, test := range tests {
+		got, err := ParseRecurrence(test.value)
+		if got != test.want || err != test.err {
+			t.Errorf("ParseRecurrence(%s) = %v, %v, want %v, %v", test.value, got, err, test.want, test.err)
+		}
+	}
+}
+
+func TestRecur(t *testing.T) {
+	tests := []struct {
+		task string
+		done string // completion date
+		want string // next occurrence, empty if none
+		err  error
+	}{
+		{"Call mom", "2014-06-04", "", nil},
+		{"Water plants rec:1w", "2014-06-04", "Water plants rec:1w due:2014-06-11", nil},
+		{"Pay rent due:2014-06-01 rec:1m", "2014-06-04", "Pay rent due:2014-07-04 rec:1m", nil},
+		{"Pay rent due:2014-06-01 rec:+1m", "2014-06-04", "Pay rent due:2014-07-01 rec:+1m", nil},
+		{"Pay rent due:2014-01-31 rec:+1m", "2014-02-02", "Pay rent due:2014-02-28 rec:+1m", nil},
+		{"2014-05-01 Report due:2014-06-05 t:2014-06-02 rec:1w", "2014-06-04",
+			"2014-06-04 Report due:2014-06-11 t:2014-06-08 rec:1w", nil},
+		{"Backup t:2014-06-01 rec:+2d", "2014-06-04", "Backup t:2014-06-03 rec:+2d", nil},
+		{"(A) Standup @work rec:b", "2014-06-06", "(A) Standup @work rec:b due:2014-06-09", nil},
+		{"Broken rec:0d", "2014-06-04", "", ErrInvalidRecurrence},
+	}
+	for _, test := range tests {
+		task, _ := ParseTask(test.task)
+		task.Id = 3
+		next, err := task.Recur(date(test.done))
+		if err != test.err {
+			t.Errorf("Recur(%s): error %v, want %v", test.task, err, test.err)
+			continue
+		}
+		if task.String() != test.task {
+			t.Errorf("Recur(%s) changed the task into %s", test.task, task)
+		}
+		switch {
+		case next == nil && test.want != "":
+			t.Errorf("Recur(%s) = nil, want %s", test.task, test.want)
+		case next != nil && test.want == "":
+			t.Errorf("Recur(%s) = %s, want nil", test.task, next)
+		case next != nil:
+			if next.String() != test.want || next.Raw != test.want {
+				t.Errorf("Recur(%s) = %s, want %s", test.task, next, test.want)
+			}
+			if next.Id != 0 || next.Completed {
+				t.Errorf("Recur(%s) returned task %d, completed %v", test.task, next.Id, next.Completed)
+			}
+		}
+	}
+}
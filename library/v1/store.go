@@ -0,0 +1,332 @@
+// Copyright (c) 2014, Mauro Toffanin. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package todotxt
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// A Store is a storage backend for a todo.txt task list.
+//
+// Tasks returned by Load are numbered from 1 in the order they are stored.
+type Store interface {
+	// Load reads all the tasks from the store.
+	Load() (TaskList, error)
+
+	// Save replaces the content of the store with tasks.
+	Save(tasks TaskList) error
+
+	// Append adds tasks at the end of the store.
+	Append(tasks ...Task) error
+
+	// Watch notifies every change of the store on the returned channel,
+	// until done is closed.
+	Watch(done <-chan struct{}) (<-chan struct{}, error)
+}
+
+// A Locker is a Store which can be locked against the changes of other
+// processes, so that a Load and the following Save or Append are atomic.
+type Locker interface {
+	// Lock waits until the store is locked, and returns the function which
+	// releases the lock.
+	Lock() (unlock func() error, err error)
+}
+
+// ErrLocked is returned when a store stays locked by another process for
+// longer than the lock timeout.
+var ErrLocked = errors.New("todotxt: store locked by another process")
+
+// FileStore is a Store backed by a todo.txt file.
+type FileStore struct {
+	Path         string        // Path of the todo.txt file
+	Perm         os.FileMode   // Permission bits used to create the file
+	PollInterval time.Duration // Interval between checks used by Watch
+	LockTimeout  time.Duration // Maximum wait for the lock used by Lock
+	Cache        *Cache        // Optional cache of the parsed file
+}
+
+// NewFileStore returns a Store that reads and writes the todo.txt file at path.
+func NewFileStore(path string) *FileStore {
+	return &FileStore{
+		Path:         path,
+		Perm:         0600,
+		PollInterval: time.Second,
+		LockTimeout:  10 * time.Second,
+	}
+}
+
+// Load reads all the tasks from the file, or from the cache if the file
+// didn't change since it was last parsed.
+// A missing file is reported as an empty list of tasks.
+func (s *FileStore) Load() (TaskList, error) {
+	if s.Cache != nil {
+		index, err := s.Cache.Load(s.Path)
+		if err != nil {
+			return nil, err
+		}
+		return index.Tasks, nil
+	}
+
+	file, err := os.Open(s.Path)
+	if os.IsNotExist(err) {
+		return TaskList{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return NewReader(file).ReadAll()
+}
+
+// Index returns the tasks of the file along with their tag indexes.
+func (s *FileStore) Index() (*Index, error) {
+	if s.Cache != nil {
+		return s.Cache.Load(s.Path)
+	}
+	tasks, err := s.Load()
+	if err != nil {
+		return nil, err
+	}
+	return NewIndex(tasks), nil
+}
+
+// Save replaces the content of the file with tasks.
+//
+// The tasks are written to a temporary file which then replaces the original
+// one, so that readers never see a partially written file. The temporary
+// file gets the mode and, if possible, the owner of the original file; if
+// Path is a symbolic link, the file it points to is replaced instead of the
+// link.
+func (s *FileStore) Save(tasks TaskList) error {
+	path := s.Path
+	if target, err := filepath.EvalSymlinks(path); err == nil {
+		path = target
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	info, err := os.Stat(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	tmp, err := os.OpenFile(path+".tmp", os.O_WRONLY|os.O_TRUNC|os.O_CREATE, s.Perm)
+	if err != nil {
+		return err
+	}
+	if info != nil {
+		copyOwner(tmp, info)
+		err = tmp.Chmod(info.Mode().Perm())
+	}
+	if err == nil {
+		err = NewWriter(tmp).WriteAll(tasks)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Append adds tasks at the end of the file, creating the file if needed.
+func (s *FileStore) Append(tasks ...Task) error {
+	file, err := os.OpenFile(s.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, s.Perm)
+	if err != nil {
+		return err
+	}
+	if err = NewWriter(file).WriteAll(tasks); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Lock locks the file by creating a lock file next to it (Path + ".lock"),
+// waiting up to LockTimeout for the other processes to release it. It
+// returns ErrLocked if the lock file is still there after LockTimeout: it
+// can be removed by hand if it was left behind by a crashed process.
+func (s *FileStore) Lock() (func() error, error) {
+	path := s.Path + ".lock"
+	deadline := time.Now().Add(s.LockTimeout)
+	for {
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, s.Perm)
+		if err == nil {
+			file.Close()
+			return func() error { return os.Remove(path) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		if time.Now().After(deadline) {
+			return nil, &os.PathError{Op: "lock", Path: path, Err: ErrLocked}
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// Watch notifies every change of the file on the returned channel, until
+// done is closed.
+//
+// Changes are detected with the native file notifications of the platform
+// (inotify on Linux) when available; otherwise Watch falls back on polling
+// the size and the modification time of the file every PollInterval.
+//
+// When the file is a symbolic link both the link and its target are watched:
+// Save replaces the target, inside the directory of the target.
+func (s *FileStore) Watch(done <-chan struct{}) (<-chan struct{}, error) {
+	if _, err := os.Stat(filepath.Dir(s.Path)); err != nil {
+		return nil, err
+	}
+	paths := []string{s.Path}
+	if target, err := filepath.EvalSymlinks(s.Path); err == nil && target != filepath.Clean(s.Path) {
+		paths = append(paths, target)
+	}
+	changes := make(chan struct{}, 1)
+	if !watchFiles(paths, done, changes) {
+		go s.poll(done, changes)
+	}
+	return changes, nil
+}
+
+// poll checks the file for changes until done is closed.
+func (s *FileStore) poll(done <-chan struct{}, changes chan<- struct{}) {
+	defer close(changes)
+
+	interval := s.PollInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last := s.stat()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if current := s.stat(); current != last {
+				last = current
+				notify(changes)
+			}
+		}
+	}
+}
+
+// fileState holds the attributes used to detect changes of a file.
+type fileState struct {
+	size    int64
+	modTime time.Time
+	exists  bool
+}
+
+// stat returns the current state of the file.
+func (s *FileStore) stat() fileState {
+	info, err := os.Stat(s.Path)
+	if err != nil {
+		return fileState{}
+	}
+	return fileState{info.Size(), info.ModTime(), true}
+}
+
+// MemoryStore is a Store which keeps the tasks in memory. It is meant for
+// tests and for applications embedding go-todo without a filesystem.
+type MemoryStore struct {
+	mutex    sync.Mutex
+	tasks    TaskList
+	watchers []chan struct{}
+}
+
+// NewMemoryStore returns a Store holding a copy of tasks.
+func NewMemoryStore(tasks TaskList) *MemoryStore {
+	s := &MemoryStore{}
+	s.tasks = append(s.tasks, tasks...)
+	return s
+}
+
+// Load returns a copy of the tasks held by the store.
+func (s *MemoryStore) Load() (TaskList, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	tasks := make(TaskList, len(s.tasks))
+	for i, task := range s.tasks {
+		task.Id = uint64(i + 1)
+		tasks[i] = task
+	}
+	return tasks, nil
+}
+
+// Save replaces the tasks held by the store.
+func (s *MemoryStore) Save(tasks TaskList) error {
+	s.mutex.Lock()
+	s.tasks = append(TaskList{}, tasks...)
+	s.mutex.Unlock()
+
+	s.notify()
+	return nil
+}
+
+// Append adds tasks at the end of the store.
+func (s *MemoryStore) Append(tasks ...Task) error {
+	s.mutex.Lock()
+	s.tasks = append(s.tasks, tasks...)
+	s.mutex.Unlock()
+
+	s.notify()
+	return nil
+}
+
+// Watch notifies every Save and Append on the returned channel, until done
+// is closed.
+func (s *MemoryStore) Watch(done <-chan struct{}) (<-chan struct{}, error) {
+	changes := make(chan struct{}, 1)
+
+	s.mutex.Lock()
+	s.watchers = append(s.watchers, changes)
+	s.mutex.Unlock()
+
+	go func() {
+		<-done
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		for i, w := range s.watchers {
+			if w == changes {
+				s.watchers = append(s.watchers[:i], s.watchers[i+1:]...)
+				break
+			}
+		}
+		close(changes)
+	}()
+	return changes, nil
+}
+
+// notify wakes up all the watchers of the store.
+func (s *MemoryStore) notify() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, w := range s.watchers {
+		notify(w)
+	}
+}
+
+// notify sends a change notification without blocking; a pending
+// notification already covers the new change.
+func notify(changes chan<- struct{}) {
+	select {
+	case changes <- struct{}{}:
+	default:
+	}
+}
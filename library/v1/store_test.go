@@ -0,0 +1,112 @@
+// Copyright (c) 2014, Mauro Toffanin. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package todotxt
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileStoreSave(t *testing.T) {
+	dir, err := ioutil.TempDir("", "todotxt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// todo.txt is a link to a shared file, readable by the group
+	target := filepath.Join(dir, "shared.txt")
+	if err := ioutil.WriteFile(target, []byte("Call mom\n"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(target, 0640); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "todo.txt")
+	if err := os.Symlink(target, link); err != nil {
+		t.Skip("symbolic links not supported:", err)
+	}
+
+	store := NewFileStore(link)
+	if err := store.Save(parseTasks(t, "Call mom", "Buy milk")); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Lstat(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("%s replaced by a regular file", link)
+	}
+	info, err = os.Stat(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0640 {
+		t.Errorf("mode of %s changed to %v, want %v", target, perm, os.FileMode(0640))
+	}
+	data, err := ioutil.ReadFile(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Call mom\nBuy milk\n"; string(data) != want {
+		t.Errorf("%s holds %q, want %q", target, data, want)
+	}
+
+	// a missing file is created with Perm
+	path := filepath.Join(dir, "new.txt")
+	if err := NewFileStore(path).Save(parseTasks(t, "Call mom")); err != nil {
+		t.Fatal(err)
+	}
+	if info, err = os.Stat(path); err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("%s created with mode %v, want %v", path, perm, os.FileMode(0600))
+	}
+}
+
+func TestFileStoreWatchSymlink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "todotxt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// todo.txt is a link into another directory (ex.: a synced folder)
+	target := filepath.Join(dir, "sync", "todo.txt")
+	if err := os.Mkdir(filepath.Dir(target), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(target, []byte("Call mom\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "todo.txt")
+	if err := os.Symlink(target, link); err != nil {
+		t.Skip("symbolic links not supported:", err)
+	}
+
+	store := NewFileStore(link)
+	store.PollInterval = 10 * time.Millisecond
+	done := make(chan struct{})
+	defer close(done)
+	changes, err := store.Watch(done)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Save(parseTasks(t, "Call mom", "Buy milk")); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-changes:
+	case <-time.After(5 * time.Second):
+		t.Errorf("no change notified after saving %s", link)
+	}
+}
@@ -0,0 +1,205 @@
+// Copyright (c) 2014, Mauro Toffanin. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package todotxt
+
+import (
+	"strings"
+	"time"
+)
+
+// Add-on tags decoded into the fields of a Task.
+const (
+	DueTag       = "due" // due date (ex.: due:2014-06-05)
+	ThresholdTag = "t"   // threshold date, before which the task can't be started
+	HiddenTag    = "h"   // h:1 hides the task from the listings
+)
+
+// String returns the task formatted as a single todo.txt line.
+//
+// The completion mark, the completion date, the priority and the created date
+// are rebuilt from the fields of the task; the remaining text is taken from
+// Todo as is.
+func (t *Task) String() string {
+	parts := []string{}
+	if t.Completed {
+		parts = append(parts, "x")
+		if !t.CompletedDate.IsZero() {
+			parts = append(parts, t.CompletedDate.Format(DateLayout))
+		}
+	}
+	if t.Priority != "" {
+		parts = append(parts, "("+t.Priority+")")
+	}
+	if !t.CreatedDate.IsZero() {
+		parts = append(parts, t.CreatedDate.Format(DateLayout))
+	}
+	if t.Todo != "" {
+		parts = append(parts, t.Todo)
+	}
+	return strings.Join(parts, " ")
+}
+
+// Complete marks the task as done on the given date.
+// A zero date leaves the completion date unset.
+func (t *Task) Complete(date time.Time) {
+	t.Completed = true
+	t.CompletedDate = date
+}
+
+// Reopen marks the task as not done and clears its completion date.
+func (t *Task) Reopen() {
+	t.Completed = false
+	t.CompletedDate = time.Time{}
+}
+
+// Deferred returns true if the task can't be started yet on the given day,
+// because of a threshold date (t: tag) after it.
+func (t *Task) Deferred(day time.Time) bool {
+	return !t.ThresholdDate.IsZero() && t.ThresholdDate.Format(DateLayout) > day.Format(DateLayout)
+}
+
+// Tag returns the value of the add-on tag named key (ex.: due:2014-06-05).
+// It returns false if the task has no such tag.
+func (t *Task) Tag(key string) (string, bool) {
+	value, ok := t.AdditionalTags[key]
+	return value, ok
+}
+
+// SetTag sets the value of the add-on tag named key, replacing the existing
+// one in place or appending a new tag at the end of the task text.
+func (t *Task) SetTag(key, value string) {
+	if old, ok := t.AdditionalTags[key]; ok {
+		t.replaceWord(key+":"+old, key+":"+value)
+		return
+	}
+	t.appendWord(key + ":" + value)
+}
+
+// RemoveTag removes the add-on tag named key from the task text.
+func (t *Task) RemoveTag(key string) {
+	if old, ok := t.AdditionalTags[key]; ok {
+		t.replaceWord(key+":"+old, "")
+	}
+}
+
+// AddProject adds a project (ex.: +cleaning) to the task, unless the task
+// already belongs to it.
+func (t *Task) AddProject(project string) {
+	if !hasWord(t.Projects, project) {
+		t.appendWord(project)
+	}
+}
+
+// RemoveProject removes a project (ex.: +cleaning) from the task text.
+func (t *Task) RemoveProject(project string) {
+	t.replaceWord(project, "")
+}
+
+// AddContext adds a context (ex.: @grocery) to the task, unless the task
+// already has it.
+func (t *Task) AddContext(context string) {
+	if !hasWord(t.Contexts, context) {
+		t.appendWord(context)
+	}
+}
+
+// RemoveContext removes a context (ex.: @grocery) from the task text.
+func (t *Task) RemoveContext(context string) {
+	t.replaceWord(context, "")
+}
+
+// appendWord adds a word at the end of the task text.
+func (t *Task) appendWord(word string) {
+	if t.Todo == "" {
+		t.Todo = word
+	} else {
+		t.Todo += " " + word
+	}
+	t.parseTags()
+}
+
+// replaceWord replaces every occurrence of the word old inside the task text
+// with the word new. An empty new removes the word.
+func (t *Task) replaceWord(old, new string) {
+	words := strings.Fields(t.Todo)
+	result := words[:0]
+	for _, word := range words {
+		if word == old {
+			if new == "" {
+				continue
+			}
+			word = new
+		}
+		result = append(result, word)
+	}
+	t.Todo = strings.Join(result, " ")
+	t.parseTags()
+}
+
+// parseTags scans the task text and collects contexts, projects and add-on
+// tags. Known add-on tags (ex.: due:) are decoded into the matching fields.
+func (t *Task) parseTags() {
+	t.Projects = nil
+	t.Contexts = nil
+	t.AdditionalTags = map[string]string{}
+	t.DueDate = time.Time{}
+	t.ThresholdDate = time.Time{}
+	t.Hidden = false
+
+	for _, token := range strings.Fields(t.Todo) {
+		switch {
+		case len(token) > 1 && token[0] == '@':
+			t.Contexts = append(t.Contexts, token)
+		case len(token) > 1 && token[0] == '+':
+			t.Projects = append(t.Projects, token)
+		default:
+			if key, value, ok := splitTag(token); ok {
+				t.AdditionalTags[key] = value
+			}
+		}
+	}
+
+	t.DueDate, _ = t.dateTag(DueTag)
+	t.ThresholdDate, _ = t.dateTag(ThresholdTag)
+	t.Hidden = t.AdditionalTags[HiddenTag] == "1"
+}
+
+// dateTag returns the date held by the add-on tag named key, or false if the
+// task has no such tag or if its value isn't a date.
+func (t *Task) dateTag(key string) (time.Time, bool) {
+	value, ok := t.Tag(key)
+	if !ok {
+		return time.Time{}, false
+	}
+	date, err := time.Parse(DateLayout, value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return date, true
+}
+
+// splitTag splits an add-on tag (key:value) into its key and value.
+// Words like URLs (ex.: http://example.com) aren't considered tags.
+func splitTag(token string) (string, string, bool) {
+	i := strings.IndexRune(token, ':')
+	if i <= 0 || i == len(token)-1 {
+		return "", "", false
+	}
+	key, value := token[:i], token[i+1:]
+	if strings.HasPrefix(value, "/") || key[0] == '@' || key[0] == '+' {
+		return "", "", false
+	}
+	return key, value, true
+}
+
+// hasWord returns true if words contains word.
+func hasWord(words []string, word string) bool {
+	for _, w := range words {
+		if w == word {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+// Copyright (c) 2014, Mauro Toffanin. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package todotxt
+
+// ParentTag is the add-on tag linking a subtask to its parent task, through
+// the stable identifier of the parent (ex.: p:3f2a9c01, see Task.StableId).
+const ParentTag = "p"
+
+// Parent returns the parent of task within the list, or nil if task isn't a
+// subtask or if its parent isn't in the list.
+func (tasks TaskList) Parent(task *Task) *Task {
+	ref, ok := task.Tag(ParentTag)
+	if !ok {
+		return nil
+	}
+	for i := range tasks {
+		if id := tasks[i].StableId(); id != "" && id == ref && &tasks[i] != task {
+			return &tasks[i]
+		}
+	}
+	return nil
+}
+
+// Children returns the subtasks of task within the list, in list order.
+func (tasks TaskList) Children(task *Task) []*Task {
+	children := []*Task{}
+	id := task.StableId()
+	if id == "" {
+		return children
+	}
+	for i := range tasks {
+		if ref, ok := tasks[i].Tag(ParentTag); ok && ref == id && &tasks[i] != task {
+			children = append(children, &tasks[i])
+		}
+	}
+	return children
+}
+
+// Descendants returns the subtasks of task within the list, recursively, in
+// depth-first order.
+func (tasks TaskList) Descendants(task *Task) []*Task {
+	descendants := []*Task{}
+	visited := map[*Task]bool{task: true}
+	var visit func(t *Task)
+	visit = func(t *Task) {
+		for _, child := range tasks.Children(t) {
+			if !visited[child] {
+				visited[child] = true
+				descendants = append(descendants, child)
+				visit(child)
+			}
+		}
+	}
+	visit(task)
+	return descendants
+}
+
+// Walk calls fn for every task of the list in depth-first order, along with
+// the depth of the task in the tree: 0 for the root tasks (the tasks without
+// a parent in the list), 1 for their subtasks and so on. The tasks which are
+// visited at the same depth are in list order.
+//
+// Every task is visited once, even if the parent links form a cycle. Walk
+// stops at the first error returned by fn and returns it.
+func (tasks TaskList) Walk(fn func(task *Task, depth int) error) error {
+	// index the children by the stable identifier of their parent
+	ids := map[string]bool{}
+	for i := range tasks {
+		if id := tasks[i].StableId(); id != "" {
+			ids[id] = true
+		}
+	}
+	children := map[string][]*Task{}
+	roots := []*Task{}
+	for i := range tasks {
+		if ref, ok := tasks[i].Tag(ParentTag); ok && ids[ref] && ref != tasks[i].StableId() {
+			children[ref] = append(children[ref], &tasks[i])
+		} else {
+			roots = append(roots, &tasks[i])
+		}
+	}
+
+	visited := map[*Task]bool{}
+	var visit func(t *Task, depth int) error
+	visit = func(t *Task, depth int) error {
+		if visited[t] {
+			return nil
+		}
+		visited[t] = true
+		if err := fn(t, depth); err != nil {
+			return err
+		}
+		if id := t.StableId(); id != "" {
+			for _, child := range children[id] {
+				if err := visit(child, depth+1); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	for _, root := range roots {
+		if err := visit(root, 0); err != nil {
+			return err
+		}
+	}
+
+	// the tasks of a cycle have no root
+	for i := range tasks {
+		if err := visit(&tasks[i], 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+// Copyright (c) 2014, Mauro Toffanin. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package todotxt
+
+import (
+	"math"
+	"time"
+)
+
+// Factors of the urgency of a task, as named in the breakdowns.
+const (
+	UrgencyPriority = "priority" // priority, from (A) down to (Z)
+	UrgencyDue      = "due"      // proximity of the due date
+	UrgencyAge      = "age"      // time elapsed since the created date
+	UrgencyBlocking = "blocking" // other open tasks depend on the task
+	UrgencyBlocked  = "blocked"  // the task depends on open tasks
+)
+
+// An UrgencyModel computes the urgency of the tasks: the higher the urgency,
+// the sooner a task should be done.
+//
+// The urgency is a sum of factors, each one being a weight multiplied by a
+// ratio between 0 and 1:
+//
+//	priority   1 for (A), 0.65 for (B), 0.3 for (C), 0.1 for the others
+//	due        1 a week after the due date or later, down to 0.2 two weeks
+//	           before it or earlier
+//	age        1 a year after the created date or later, 0 on the same day
+//	blocking   1 if other open tasks depend on the task (dep: tags)
+//	blocked    1 if the task depends on open tasks
+//
+// plus the weight of every project and context of the task.
+type UrgencyModel struct {
+	Weights map[string]float64 // Weights of the factors, by name
+	Tags    map[string]float64 // Weights of the projects and contexts (ex.: +work)
+	Tasks   TaskList           // List of the tasks, for the dependencies
+	Now     time.Time          // Reference date of the due dates and ages
+}
+
+// An UrgencyFactor is a term of the urgency of a task.
+type UrgencyFactor struct {
+	Name  string // Factor, or project or context
+	Value float64
+}
+
+// DefaultUrgencyWeights holds the default weights of the urgency factors.
+var DefaultUrgencyWeights = map[string]float64{
+	UrgencyPriority: 6,
+	UrgencyDue:      12,
+	UrgencyAge:      2,
+	UrgencyBlocking: 8,
+	UrgencyBlocked:  -5,
+}
+
+// NewUrgencyModel returns an UrgencyModel with the default weights, for the
+// tasks of a list on the given date.
+func NewUrgencyModel(tasks TaskList, now time.Time) *UrgencyModel {
+	m := &UrgencyModel{Weights: map[string]float64{}, Tags: map[string]float64{}, Tasks: tasks, Now: now}
+	for name, weight := range DefaultUrgencyWeights {
+		m.Weights[name] = weight
+	}
+	return m
+}
+
+// priorityRatios holds the ratio of the priority factor of the highest
+// priorities; lower priorities get lowPriorityRatio.
+var priorityRatios = map[string]float64{"A": 1, "B": 0.65, "C": 0.3}
+
+const lowPriorityRatio = 0.1
+
+// Factors returns the non-zero factors of the urgency of a task, in a stable
+// order. Completed tasks have no urgency.
+func (m *UrgencyModel) Factors(t *Task) []UrgencyFactor {
+	factors := []UrgencyFactor{}
+	if t.Completed {
+		return factors
+	}
+	add := func(name string, ratio float64) {
+		if value := m.Weights[name] * ratio; value != 0 {
+			factors = append(factors, UrgencyFactor{name, value})
+		}
+	}
+
+	if t.Priority != "" {
+		ratio, ok := priorityRatios[t.Priority]
+		if !ok {
+			ratio = lowPriorityRatio
+		}
+		add(UrgencyPriority, ratio)
+	}
+	if !t.DueDate.IsZero() {
+		add(UrgencyDue, m.dueRatio(t.DueDate))
+	}
+	if !t.CreatedDate.IsZero() {
+		add(UrgencyAge, math.Min(math.Max(m.days(t.CreatedDate), 0)/365, 1))
+	}
+	if m.Tasks != nil {
+		for _, dependent := range m.Tasks.Dependents(t) {
+			if !dependent.Completed {
+				add(UrgencyBlocking, 1)
+				break
+			}
+		}
+		if m.Tasks.Blocked(t) {
+			add(UrgencyBlocked, 1)
+		}
+	}
+	for _, tag := range append(append([]string{}, t.Projects...), t.Contexts...) {
+		if weight := m.Tags[tag]; weight != 0 {
+			factors = append(factors, UrgencyFactor{tag, weight})
+		}
+	}
+	return factors
+}
+
+// dueRatio returns the ratio of the due factor: 1 from a week after the due
+// date, down to 0.2 from two weeks before it, linearly in between.
+func (m *UrgencyModel) dueRatio(due time.Time) float64 {
+	overdue := m.days(due)
+	switch {
+	case overdue >= 7:
+		return 1
+	case overdue <= -14:
+		return 0.2
+	}
+	return 0.2 + (overdue+14)*0.8/21
+}
+
+// days returns the number of days elapsed from date to the date of Now.
+func (m *UrgencyModel) days(date time.Time) float64 {
+	now := m.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	return math.Floor(today.Sub(day).Hours()/24 + 0.5)
+}
+
+// Urgency returns the urgency of the task according to a model, or to the
+// default model if m is nil (which ignores the dependencies of the task).
+func (t *Task) Urgency(m *UrgencyModel) float64 {
+	if m == nil {
+		m = NewUrgencyModel(nil, time.Now())
+	}
+	urgency := 0.0
+	for _, factor := range m.Factors(t) {
+		urgency += factor.Value
+	}
+	return urgency
+}
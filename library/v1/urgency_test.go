@@ -0,0 +1,76 @@
+// Copyright (c) 2014, Mauro Toffanin. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package todotxt
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestUrgencyFactors(t *testing.T) {
+	now := date("2014-06-04").Add(15 * time.Hour)
+	tests := []struct {
+		tasks []string // the first task is scored, the others are its list
+		want  []UrgencyFactor
+	}{
+		{[]string{"Call mom"}, nil},
+		{[]string{"(A) Call mom"}, []UrgencyFactor{{"priority", 6}}},
+		{[]string{"(B) Call mom"}, []UrgencyFactor{{"priority", 3.9}}},
+		{[]string{"(Z) Call mom"}, []UrgencyFactor{{"priority", 0.6}}},
+
+		// due ratio: 0.2 up to 14 days before, 1 from 7 days after
+		{[]string{"Pay rent due:2014-07-01"}, []UrgencyFactor{{"due", 2.4}}},
+		{[]string{"Pay rent due:2014-06-18"}, []UrgencyFactor{{"due", 2.4}}},
+		{[]string{"Pay rent due:2014-06-17"}, []UrgencyFactor{{"due", 12 * (0.2 + 0.8/21)}}},
+		{[]string{"Pay rent due:2014-06-04"}, []UrgencyFactor{{"due", 12 * (0.2 + 14*0.8/21)}}},
+		{[]string{"Pay rent due:2014-05-29"}, []UrgencyFactor{{"due", 12 * (0.2 + 20*0.8/21)}}},
+		{[]string{"Pay rent due:2014-05-28"}, []UrgencyFactor{{"due", 12}}},
+		{[]string{"Pay rent due:2013-01-01"}, []UrgencyFactor{{"due", 12}}},
+
+		// age: up to a year
+		{[]string{"2014-06-04 Call mom"}, nil},
+		{[]string{"2014-07-01 Call mom"}, nil},
+		{[]string{"2014-03-06 Call mom"}, []UrgencyFactor{{"age", 2 * 90.0 / 365}}},
+		{[]string{"2013-06-04 Call mom"}, []UrgencyFactor{{"age", 2}}},
+		{[]string{"2010-01-01 Call mom"}, []UrgencyFactor{{"age", 2}}},
+
+		// dependencies within the list
+		{[]string{"Buy paint id:a", "Paint the fence dep:a"}, []UrgencyFactor{{"blocking", 8}}},
+		{[]string{"Buy paint id:a", "x 2014-06-01 Paint the fence dep:a"}, nil},
+		{[]string{"Paint the fence dep:a", "Buy paint id:a"}, []UrgencyFactor{{"blocked", -5}}},
+		{[]string{"Paint the fence dep:a", "x 2014-06-01 Buy paint id:a"}, nil},
+
+		// projects and contexts
+		{[]string{"Report +work @someday +home"}, []UrgencyFactor{{"+work", 2}, {"@someday", -3}}},
+
+		{[]string{"(A) 2014-03-06 Report due:2014-05-28 +work id:r", "Review dep:r"},
+			[]UrgencyFactor{{"priority", 6}, {"due", 12}, {"age", 2 * 90.0 / 365}, {"blocking", 8}, {"+work", 2}}},
+
+		// completed tasks have no urgency
+		{[]string{"x 2014-06-01 (A) 2014-01-01 Report due:2014-05-01 +work id:r", "Review dep:r"}, nil},
+	}
+	for _, test := range tests {
+		tasks := parseTasks(t, test.tasks...)
+		m := NewUrgencyModel(tasks, now)
+		m.Tags["+work"], m.Tags["@someday"] = 2, -3
+
+		got := m.Factors(&tasks[0])
+		sum := 0.0
+		ok := len(got) == len(test.want)
+		for i := range test.want {
+			sum += test.want[i].Value
+			if ok && (got[i].Name != test.want[i].Name || math.Abs(got[i].Value-test.want[i].Value) > 1e-9) {
+				ok = false
+			}
+		}
+		if !ok {
+			t.Errorf("Factors(%s) = %v, want %v", test.tasks[0], got, test.want)
+		}
+		if urgency := tasks[0].Urgency(m); math.Abs(urgency-sum) > 1e-9 {
+			t.Errorf("Urgency(%s) = %v, want %v", test.tasks[0], urgency, sum)
+		}
+	}
+}
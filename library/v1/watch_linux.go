@@ -0,0 +1,83 @@
+// Copyright (c) 2014, Mauro Toffanin. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build linux
+// +build linux
+
+package todotxt
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+// inotifyMask selects the events that can change the content of a file,
+// including the ones generated by editors which replace the whole file.
+const inotifyMask = syscall.IN_CLOSE_WRITE | syscall.IN_MODIFY | syscall.IN_CREATE |
+	syscall.IN_DELETE | syscall.IN_MOVED_TO | syscall.IN_MOVED_FROM
+
+// watchFiles notifies the changes of the files at paths using inotify(7).
+//
+// The directories of the files are watched, instead of the files themselves,
+// so that a file can be deleted and created again without losing the watch.
+// It returns false if inotify isn't available.
+func watchFiles(paths []string, done <-chan struct{}, changes chan<- struct{}) bool {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return false
+	}
+
+	// names of the watched files, by watch descriptor of their directory
+	names := map[int32]map[string]bool{}
+	for _, path := range paths {
+		wd, err := syscall.InotifyAddWatch(fd, filepath.Dir(path), inotifyMask)
+		if err != nil {
+			syscall.Close(fd)
+			return false
+		}
+		if names[int32(wd)] == nil {
+			names[int32(wd)] = map[string]bool{}
+		}
+		names[int32(wd)][filepath.Base(path)] = true
+	}
+
+	// a non-blocking file can be closed while a Read is pending
+	file := os.NewFile(uintptr(fd), "inotify")
+	go func() {
+		<-done
+		file.Close()
+	}()
+
+	go func() {
+		defer close(changes)
+
+		buffer := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+		for {
+			n, err := file.Read(buffer)
+			if err != nil {
+				return
+			}
+			for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+				event := (*syscall.InotifyEvent)(unsafe.Pointer(&buffer[offset]))
+				start := offset + syscall.SizeofInotifyEvent
+				offset = start + int(event.Len)
+
+				// event.Name is padded with NUL bytes
+				eventName := string(buffer[start:offset])
+				for i := 0; i < len(eventName); i++ {
+					if eventName[i] == 0 {
+						eventName = eventName[:i]
+						break
+					}
+				}
+				if names[event.Wd][eventName] {
+					notify(changes)
+				}
+			}
+		}
+	}()
+	return true
+}
@@ -0,0 +1,14 @@
+// Copyright (c) 2014, Mauro Toffanin. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !linux
+// +build !linux
+
+package todotxt
+
+// watchFiles reports that native file notifications aren't available on this
+// platform, so that Watch falls back on polling.
+func watchFiles(paths []string, done <-chan struct{}, changes chan<- struct{}) bool {
+	return false
+}
@@ -0,0 +1,48 @@
+// Copyright (c) 2014, Mauro Toffanin. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package todotxt
+
+import (
+	"bufio"
+	"io"
+)
+
+// A Writer writes tasks to a todo.txt file.
+//
+// As returned by NewWriter, a Writer writes tasks one per line, terminated by
+// a newline character. The writes are buffered, so Flush must eventually be
+// called to ensure that the tasks have been written to the underlying
+// io.Writer.
+type Writer struct {
+	buffer *bufio.Writer // buffer used for writing io outputs
+}
+
+// NewWriter returns a new Writer that writes to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{
+		buffer: bufio.NewWriter(w),
+	}
+}
+
+// Write writes a single task to w.
+func (w *Writer) Write(task *Task) error {
+	_, err := w.buffer.WriteString(task.String() + "\n")
+	return err
+}
+
+// WriteAll writes all the tasks to w and then calls Flush.
+func (w *Writer) WriteAll(tasks TaskList) error {
+	for i := range tasks {
+		if err := w.Write(&tasks[i]); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+// Flush writes any buffered data to the underlying io.Writer.
+func (w *Writer) Flush() error {
+	return w.buffer.Flush()
+}
@@ -5,8 +5,9 @@
 package main
 
 import (
-	"fmt"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/toffanin/go-todo/commands"
 	"github.com/toffanin/go-todo/utils"
@@ -34,7 +35,9 @@ COMMANDS:
    {{end}}
 GLOBAL OPTIONS:
    {{range .Flags}}{{.}}
-   {{end}}
+   {{end}}-v{{ "\t" }}Verbose mode
+   -vv{{ "\t" }}Extra verbose mode, prints the stack traces of the errors
+
 ENVIRONMENT VARIABLES:
    TODOTXT_AUTO_ARCHIVE=0,1{{"\t"}}is equivalent to global options -a (0) / -A (1)
    TODOTXT_CFG_FILE=CONFIG_FILE{{"\t"}}is equivalent to global option -d CONFIG_FILE
@@ -48,6 +51,25 @@ ENVIRONMENT VARIABLES:
    TODOTXT_SORT_COMMAND="sort ..."{{ "\t" }}customize list output
    TODOTXT_FINAL_FILTER="sed ..."{{ "\t" }}customize list after color, P@+ hiding
    TODOTXT_SOURCEVAR=\$DONE_FILE{{ "\t" }}use another source for listcon, listproj
+   TODOTXT_AUTO_ID=0,1{{ "\t" }}adds a stable identifier to new tasks
+   TODOTXT_ID_TAG=id,uuid{{ "\t" }}add-on tag used for stable identifiers
+   TODOTXT_CACHE=0,1{{ "\t" }}caches the parsed task files on disk
+   TODOTXT_CACHE_DIR=DIR{{ "\t" }}location of the cache (default ~/.cache/todo)
+   TODOTXT_PROFILE=NAME{{ "\t" }}is equivalent to global option --profile NAME
+   TODOTXT_DATE_LOCALE=LANG{{ "\t" }}language of the date expressions (ex.: due:friday)
+   TODOTXT_WEEK_START=DAY{{ "\t" }}first day of the week (ex.: monday)
+   TODOTXT_COMPLETE_PARENT=block,cascade{{ "\t" }}completion of the tasks with open subtasks
+   TODOTXT_URGENCY="due:12 +work:2 ..."{{ "\t" }}weights of the urgency of the tasks (see 'todo help next')
+   TODOTXT_ESCALATE="2d:B overdue:A"{{ "\t" }}raises the priority of the tasks due soon (see 'todo help escalate')
+   TODOTXT_AUTO_ESCALATE=0,1{{ "\t" }}escalates the priorities on every list
+
+EXIT STATUS:
+   0{{ "\t" }}success
+   1{{ "\t" }}the command failed (ex.: a task doesn't exist)
+   2{{ "\t" }}incorrect usage of a command
+   3{{ "\t" }}invalid configuration
+   4{{ "\t" }}a file can't be read or written
+   5{{ "\t" }}malformed input
 
 `
 
@@ -74,8 +96,20 @@ OPTIONS:
 
 func main() {
 
-	// Load Todo.txt CLI environment variables
-	utils.LoadConfig()
+	// Load Todo.txt CLI environment variables; the configuration is loaded
+	// before parsing the command line, so the profile is looked up first
+	opts, args := scanArgs(os.Args)
+	loader := utils.NewLoader()
+	loader.Profile = opts.profile
+	cfg, err := loader.Load()
+	if err != nil {
+		exit(err, opts.verbose)
+	}
+	if opts.verbose > 0 {
+		cfg.Override("TODOTXT_VERBOSE", strconv.Itoa(opts.verbose), utils.OriginCommandLine)
+	}
+	utils.SetConfig(cfg)
+	session := commands.NewSession(cfg)
 
 	// Initialize the templates for help sections
 	cli.AppHelpTemplate = appHelpTemplate
@@ -94,14 +128,26 @@ func main() {
 		cli.BoolFlag{"t", "Prefixes the current date to a task automatically when it's added"},
 		cli.BoolFlag{"T", "Do not prefix the current date to a task automatically when it's added"},
 		cli.BoolFlag{"f", "Forces actions without confirmation or interactive input"},
+		cli.StringFlag{"profile", "", "Loads the settings of the profile NAME"},
 	}
 	app.Commands = []cli.Command{
-		commands.GetEnv(),
-		commands.GetInit(),
-		commands.GetShorthelp(),
-		commands.GetAdd(),
-		commands.GetAddm(),
-		commands.GetList(),
+		commands.GetEnv(session),
+		commands.GetConfig(session),
+		commands.GetProfile(session),
+		commands.GetDoctor(session),
+		commands.GetInit(session),
+		commands.GetShorthelp(session),
+		commands.GetAdd(session),
+		commands.GetAddm(session),
+		commands.GetDo(session),
+		commands.GetList(session),
+		commands.GetListproj(session),
+		commands.GetListcon(session),
+		commands.GetNext(session),
+		commands.GetEscalate(session),
+		commands.GetGraph(session),
+		commands.GetMergeDriver(session),
+		commands.GetIds(session),
 		/*{
 			Name:  "status",
 			Usage: "Obtain a summary of the todo.txt structure",
@@ -114,8 +160,51 @@ func main() {
 		},*/
 	}
 
-	if err := app.Run(os.Args); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+	if err := app.Run(args); err != nil {
+		session.Err = utils.WrapError(utils.ErrUsage, err, "")
+	}
+	if session.Err != nil {
+		exit(session.Err, cfg.Verbose)
+	}
+}
+
+// exit reports err to the user, then exits with the matching exit status.
+func exit(err error, verbose int) {
+	utils.PrintError(os.Stderr, err, verbose)
+	os.Exit(utils.ExitCode(err))
+}
+
+// globalOptions holds the global options needed before parsing the command
+// line.
+type globalOptions struct {
+	profile string // --profile NAME
+	verbose int    // -v (1) or -vv (2)
+}
+
+// scanArgs looks up the global options preceding the command which are needed
+// to load the configuration. The verbosity options (-v, -vv) are removed from
+// the returned arguments, since -v is reserved for --version by the cli.
+func scanArgs(args []string) (globalOptions, []string) {
+	opts := globalOptions{}
+	rest := []string{args[0]}
+	for i := 1; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "-v" || arg == "-vv":
+			opts.verbose += len(arg) - 1
+			continue
+		case (arg == "--profile" || arg == "-profile") && i+1 < len(args):
+			opts.profile = args[i+1]
+			rest = append(rest, arg)
+			i++
+		case strings.HasPrefix(arg, "--profile="):
+			opts.profile = strings.TrimPrefix(arg, "--profile=")
+		case strings.HasPrefix(arg, "-profile="):
+			opts.profile = strings.TrimPrefix(arg, "-profile=")
+		case !strings.HasPrefix(arg, "-"):
+			// the command and its arguments
+			return opts, append(rest, args[i:]...)
+		}
+		rest = append(rest, args[i])
 	}
+	return opts, rest
 }
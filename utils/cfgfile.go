@@ -0,0 +1,222 @@
+// Copyright (c) 2014, Mauro Toffanin. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package utils
+
+import (
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// A ConfigFile is a todo.cfg file which can be edited while preserving its
+// comments, the ordering of its settings and their syntax (ex.: 'export').
+type ConfigFile struct {
+	Path string
+	text string
+}
+
+// ReadConfigFile reads the configuration file at path.
+// A missing file is reported as an empty configuration file.
+func ReadConfigFile(path string) (*ConfigFile, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return &ConfigFile{Path: path, text: string(content)}, nil
+}
+
+// nopVars is a set of variables which are always unset; assignments are
+// discarded. It allows to parse a file without evaluating it.
+type nopVars struct{}
+
+func (nopVars) Lookup(name string) (string, bool) { return "", false }
+func (nopVars) Set(name, value string)            {}
+
+// assignment locates an assignment inside the text of a file.
+type assignment struct {
+	line  int // offset of the start of the line
+	stmt  int // offset of the start of the statement (ex.: 'export')
+	name  int // offset of the name of the setting
+	value int // offset of the start of the value
+	end   int // offset of the end of the value
+}
+
+// assignments returns all the assignments of the setting name outside the
+// profile sections, in file order. Every statement can hold several
+// assignments (ex.: 'export NAME=1 OTHER=2'); the arguments of the commands
+// are skipped.
+func (f *ConfigFile) assignments(name string) []assignment {
+	text := f.text[:firstProfile(f.text)]
+	s := &sourcer{input: []rune(text), line: 1, file: f.Path, vars: nopVars{}}
+
+	// byte offsets of the runes of the input
+	offsets := make([]int, 0, len(s.input)+1)
+	for i := range text {
+		offsets = append(offsets, i)
+	}
+	offsets = append(offsets, len(text))
+
+	found := []assignment{}
+	line, stmt := 0, -1 // stmt is -1 between statements
+	command := false    // the statement is a command, not a list of assignments
+	for !s.eof() {
+		switch s.peek() {
+		case '\n':
+			s.next()
+			line, stmt, command = s.pos, -1, false
+			continue
+		case ' ', '\t', '\r':
+			s.next()
+			continue
+		case ';':
+			s.next()
+			stmt, command = -1, false
+			continue
+		case '#':
+			s.skipComment()
+			continue
+		}
+
+		start := s.pos
+		if stmt < 0 {
+			stmt = start
+		}
+		word := s.name()
+		if !command && word != "" && s.peek() == '=' {
+			s.next()
+			value := s.pos
+			if _, err := s.word(0); err != nil {
+				break
+			}
+			if word == name {
+				found = append(found, assignment{offsets[line], offsets[stmt], offsets[start], offsets[value],
+					offsets[s.pos]})
+			}
+			continue
+		}
+		if !command && word == "export" && start == stmt && (s.peek() == ' ' || s.peek() == '\t') {
+			continue
+		}
+
+		// a command, or one of its arguments
+		command = true
+		s.pos = start
+		if _, err := s.word(0); err != nil {
+			break
+		}
+		if s.pos == start {
+			s.next()
+		}
+	}
+	return found
+}
+
+// Value returns the raw value of the last assignment of the setting name, as
+// written inside the file (quotes included).
+func (f *ConfigFile) Value(name string) (string, bool) {
+	found := f.assignments(name)
+	if len(found) == 0 {
+		return "", false
+	}
+	last := found[len(found)-1]
+	return f.text[last.value:last.end], true
+}
+
+// Set changes the value of the setting name. The last assignment of the
+// setting is rewritten in place; if there is none, a new 'export' statement
+// is appended at the end of the file, before the profile sections. The value
+// is double-quoted if needed, so that variables (ex.: $TODO_DIR) are still
+// expanded.
+func (f *ConfigFile) Set(name, value string) {
+	quoted := QuoteValue(value)
+
+	found := f.assignments(name)
+	if len(found) > 0 {
+		last := found[len(found)-1]
+		f.text = f.text[:last.value] + quoted + f.text[last.end:]
+		return
+	}
+
+	end := firstProfile(f.text)
+	before, after := f.text[:end], f.text[end:]
+	if before != "" && !strings.HasSuffix(before, "\n") {
+		before += "\n"
+	}
+	f.text = before + "export " + name + "=" + quoted + "\n" + after
+}
+
+// Unset removes all the assignments of the setting name outside the profile
+// sections. The other statements and assignments sharing their lines are
+// preserved (ex.: 'export NAME=1 OTHER=2' becomes 'export OTHER=2'), while
+// the lines left empty are removed along with their trailing comments. It
+// returns false if the setting wasn't assigned.
+func (f *ConfigFile) Unset(name string) bool {
+	blank := func(c byte) bool { return c == ' ' || c == '\t' }
+
+	found := f.assignments(name)
+	for i := len(found) - 1; i >= 0; i-- {
+		a := found[i]
+		before := strings.TrimSpace(f.text[a.stmt:a.name])
+		first := before == "" || before == "export"
+		end := a.end
+		for end < len(f.text) && blank(f.text[end]) {
+			end++
+		}
+		eol := end == len(f.text) || f.text[end] == '\n' || f.text[end] == '#'
+
+		var start int
+		switch {
+		case first && eol && strings.TrimSpace(f.text[a.line:a.stmt]) == "":
+			// nothing else on the line: remove the whole line
+			start = a.line
+			if n := strings.IndexByte(f.text[end:], '\n'); n >= 0 {
+				end += n + 1
+			} else {
+				end = len(f.text)
+			}
+		case first && eol:
+			// the last statement of the line
+			start, end = a.stmt, a.end
+			for start > a.line && blank(f.text[start-1]) {
+				start--
+			}
+		case first && f.text[end] == ';':
+			// remove the statement up to the next one
+			start = a.stmt
+			for end++; end < len(f.text) && blank(f.text[end]); end++ {
+			}
+		case eol || f.text[end] == ';':
+			// the last assignment of the statement
+			start, end = a.name, a.end
+			for start > a.stmt && blank(f.text[start-1]) {
+				start--
+			}
+		default:
+			// followed by other assignments of the statement
+			start = a.name
+		}
+		f.text = f.text[:start] + f.text[end:]
+	}
+	return len(found) > 0
+}
+
+// Save writes the file back to disk.
+func (f *ConfigFile) Save() error {
+	return ioutil.WriteFile(f.Path, []byte(f.text), 0600)
+}
+
+// plainValue matches the values which don't need to be quoted.
+var plainValue = regexp.MustCompile(`^[A-Za-z0-9_./:,+@%-]+$`)
+
+// QuoteValue quotes a value for a todo.cfg file with double quotes, escaping
+// the characters which would end the string. Variables are left unescaped.
+// Plain values (ex.: 1, todo.txt) are left unquoted.
+func QuoteValue(value string) string {
+	if plainValue.MatchString(value) {
+		return value
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "`", "\\`").Replace(value) + `"`
+}
@@ -0,0 +1,63 @@
+// Copyright (c) 2014, Mauro Toffanin. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package utils
+
+import (
+	"testing"
+)
+
+func TestConfigFileSet(t *testing.T) {
+	tests := []struct {
+		text, name, value string
+		want              string
+	}{
+		{"", "A", "1", "export A=1\n"},
+		{"# comment\nexport A=1 # old\n", "A", "2", "# comment\nexport A=2 # old\n"},
+		{"A=1\nA=2\n", "A", "3", "A=1\nA=3\n"},
+		{"export A=1 B=2\n", "B", "x y", "export A=1 B=\"x y\"\n"},
+		{"A=1", "B", "$A/todo.txt", "A=1\nexport B=\"$A/todo.txt\"\n"},
+		{"A=1\n[profile work]\nB=1\n", "B", "2", "A=1\nexport B=2\n[profile work]\nB=1\n"},
+		{"echo B=1\n", "B", "2", "echo B=1\nexport B=2\n"},
+		{"A=\"é\" B=1\n", "B", "2", "A=\"é\" B=2\n"},
+	}
+	for _, test := range tests {
+		f := &ConfigFile{Path: "todo.cfg", text: test.text}
+		f.Set(test.name, test.value)
+		if f.text != test.want {
+			t.Errorf("Set(%s, %s) on %q = %q, want %q", test.name, test.value, test.text, f.text, test.want)
+		}
+	}
+}
+
+func TestConfigFileUnset(t *testing.T) {
+	tests := []struct {
+		text, name string
+		want       string
+		ok         bool
+	}{
+		{"A=1\n", "B", "A=1\n", false},
+		{"# comment\nexport A=1 # old\nB=2\n", "A", "# comment\nB=2\n", true},
+		{"A=1\nB=2\nA=3", "A", "B=2\n", true},
+		{"export A=1 B=2\n", "A", "export B=2\n", true},
+		{"A=1 B=2 # both\n", "A", "B=2 # both\n", true},
+		{"A=1; echo hello\n", "A", "echo hello\n", true},
+		{"  export A=1 ;B=2\n", "A", "  B=2\n", true},
+		{"A=1\n[profile work]\nA=2\n", "A", "[profile work]\nA=2\n", true},
+		{"export A=1 B=2 # both\n", "B", "export A=1 # both\n", true},
+		{"export A=1 B=2 C=3\n", "B", "export A=1 C=3\n", true},
+		{"A=1; B=2\n", "B", "A=1;\n", true},
+		{"export X=1 A=1; echo hello\n", "A", "export X=1; echo hello\n", true},
+		{"echo A=1\nB='A=1'\n", "A", "echo A=1\nB='A=1'\n", false},
+		{"A=\"x\\\ny\" B=2\n", "A", "B=2\n", true},
+	}
+	for _, test := range tests {
+		f := &ConfigFile{Path: "todo.cfg", text: test.text}
+		ok := f.Unset(test.name)
+		if f.text != test.want || ok != test.ok {
+			t.Errorf("Unset(%s) on %q = %q, %v, want %q, %v", test.name, test.text, f.text, ok, test.want,
+				test.ok)
+		}
+	}
+}
@@ -0,0 +1,541 @@
+// Copyright (c) 2014, Mauro Toffanin. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package utils
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Config is a typed representation of the Todo.txt CLI configuration.
+//
+// Every field is bound to a setting (ex.: TodoDir is TODO_DIR), so a Config
+// can be read and changed either through its fields or by setting name with
+// Get and Set.
+type Config struct {
+	// File locations
+	TodoDir    string // TODO_DIR
+	TodoFile   string // TODO_FILE
+	DoneFile   string // DONE_FILE
+	ReportFile string // REPORT_FILE
+	ActionsDir string // TODO_ACTIONS_DIR
+
+	// App options
+	DateOnAdd bool   // TODOTXT_DATE_ON_ADD
+	Force     bool   // TODOTXT_FORCE
+	Verbose   int    // TODOTXT_VERBOSE
+	AutoId    bool   // TODOTXT_AUTO_ID
+	IdTag     string // TODOTXT_ID_TAG
+	Cache     bool   // TODOTXT_CACHE
+	CacheDir  string // TODOTXT_CACHE_DIR
+	Profile   string // TODOTXT_PROFILE
+
+	// Completion of the tasks with open subtasks: block or cascade
+	CompleteParent string // TODOTXT_COMPLETE_PARENT
+
+	// Date expressions (ex.: due:friday)
+	DateLocale string // TODOTXT_DATE_LOCALE
+	WeekStart  string // TODOTXT_WEEK_START
+
+	// Urgency model of the tasks (ex.: due:20 +work:2 @phone:-1)
+	Urgency string // TODOTXT_URGENCY
+
+	// Priority escalation of the tasks due soon (ex.: 2d:B overdue:A)
+	Escalate     string // TODOTXT_ESCALATE
+	AutoEscalate bool   // TODOTXT_AUTO_ESCALATE
+
+	// External commands used to customize the list output
+	SortCommand string // TODOTXT_SORT_COMMAND
+	FinalFilter string // TODOTXT_FINAL_FILTER
+
+	// Colors of the list output, by setting name (ex.: PRI_A, COLOR_DONE)
+	Colors map[string]string
+
+	Home string // Home directory of the user ($HOME)
+	Pwd  string // Working directory ($PWD)
+
+	// Configuration files which have been sourced, in order of precedence;
+	// the profile file isn't one of them
+	Files []string
+
+	// Profile file of the selected profile (ex.: profiles/work.cfg), if any
+	ProfileFile string
+
+	// Profiles defined by the configuration files, in lexical order
+	Profiles []string
+
+	extra   map[string]string // settings unknown to go-todo
+	origins map[string]string // where every setting has been defined
+}
+
+// Origins of the settings not defined by a configuration file.
+const (
+	OriginDefault     = "default"
+	OriginEnvironment = "environment"
+	OriginCommandLine = "command line"
+)
+
+// colorSettings lists the settings which hold the colors of the list output.
+var colorSettings = []string{
+	"PRI_A", "PRI_B", "PRI_C", "PRI_X",
+	"COLOR_DONE", "COLOR_PROJECT", "COLOR_CONTEXT", "COLOR_DATE",
+}
+
+// NewConfig returns a Config filled with the default values.
+func NewConfig() *Config {
+	c := &Config{
+		IdTag:          "id",
+		CompleteParent: "block",
+		Colors:         map[string]string{},
+		extra:          map[string]string{},
+		origins:        map[string]string{},
+	}
+	for _, name := range colorSettings {
+		c.Colors[name] = ""
+	}
+	return c
+}
+
+// fields binds the name of every typed setting to its field.
+func (c *Config) fields() map[string]interface{} {
+	return map[string]interface{}{
+		"TODO_DIR":                &c.TodoDir,
+		"TODO_FILE":               &c.TodoFile,
+		"DONE_FILE":               &c.DoneFile,
+		"REPORT_FILE":             &c.ReportFile,
+		"TODO_ACTIONS_DIR":        &c.ActionsDir,
+		"TODOTXT_SORT_COMMAND":    &c.SortCommand,
+		"TODOTXT_FINAL_FILTER":    &c.FinalFilter,
+		"TODOTXT_DATE_ON_ADD":     &c.DateOnAdd,
+		"TODOTXT_FORCE":           &c.Force,
+		"TODOTXT_VERBOSE":         &c.Verbose,
+		"TODOTXT_AUTO_ID":         &c.AutoId,
+		"TODOTXT_ID_TAG":          &c.IdTag,
+		"TODOTXT_CACHE":           &c.Cache,
+		"TODOTXT_CACHE_DIR":       &c.CacheDir,
+		"TODOTXT_PROFILE":         &c.Profile,
+		"TODOTXT_DATE_LOCALE":     &c.DateLocale,
+		"TODOTXT_WEEK_START":      &c.WeekStart,
+		"TODOTXT_COMPLETE_PARENT": &c.CompleteParent,
+		"TODOTXT_URGENCY":         &c.Urgency,
+		"TODOTXT_ESCALATE":        &c.Escalate,
+		"TODOTXT_AUTO_ESCALATE":   &c.AutoEscalate,
+	}
+}
+
+// Has returns true if name is a setting of the configuration.
+func (c *Config) Has(name string) bool {
+	if _, ok := c.fields()[name]; ok {
+		return true
+	}
+	if _, ok := c.Colors[name]; ok {
+		return true
+	}
+	_, ok := c.extra[name]
+	return ok
+}
+
+// Get returns the value of the setting name, formatted as in todo.cfg.
+// Boolean settings are formatted as "0" or "1".
+func (c *Config) Get(name string) string {
+	switch field := c.fields()[name].(type) {
+	case *string:
+		return *field
+	case *int:
+		return strconv.Itoa(*field)
+	case *bool:
+		if *field {
+			return "1"
+		}
+		return "0"
+	}
+	if value, ok := c.Colors[name]; ok {
+		return value
+	}
+	return c.extra[name]
+}
+
+// Set changes the value of the setting name, parsing the value as in
+// todo.cfg. Settings unknown to go-todo are kept as plain strings.
+// It returns an error if the value isn't valid for the setting.
+func (c *Config) Set(name, value string) error {
+	if name == "" {
+		return fmt.Errorf("empty setting name")
+	}
+
+	switch field := c.fields()[name].(type) {
+	case *string:
+		*field = value
+	case *int:
+		n := 0
+		if value != "" {
+			var err error
+			if n, err = strconv.Atoi(value); err != nil {
+				return fmt.Errorf("%s: invalid number %q", name, value)
+			}
+		}
+		*field = n
+	case *bool:
+		b := false
+		if value != "" {
+			var err error
+			if b, err = strconv.ParseBool(value); err != nil {
+				return fmt.Errorf("%s: invalid boolean %q (expected 0 or 1)", name, value)
+			}
+		}
+		*field = b
+	default:
+		if _, ok := c.Colors[name]; ok {
+			c.Colors[name] = value
+		} else {
+			c.extra[name] = value
+		}
+	}
+	return nil
+}
+
+// Override changes the value of the setting name like Set, and records where
+// the new value comes from (ex.: OriginCommandLine).
+func (c *Config) Override(name, value, origin string) error {
+	if err := c.Set(name, value); err != nil {
+		return err
+	}
+	c.origins[name] = origin
+	return nil
+}
+
+// Origin returns where the setting name has been defined: the path of a
+// configuration file, OriginEnvironment or OriginDefault.
+func (c *Config) Origin(name string) string {
+	if origin, ok := c.origins[name]; ok {
+		return origin
+	}
+	return OriginDefault
+}
+
+// Names returns the names of all the settings in lexical order.
+func (c *Config) Names() []string {
+	names := []string{}
+	for name := range c.fields() {
+		names = append(names, name)
+	}
+	for name := range c.Colors {
+		names = append(names, name)
+	}
+	for name := range c.extra {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Settings returns all the settings as a map of names and values.
+func (c *Config) Settings() map[string]string {
+	settings := map[string]string{}
+	for _, name := range c.Names() {
+		settings[name] = c.Get(name)
+	}
+	return settings
+}
+
+// A Loader builds a Config from todo.cfg files and environment variables.
+//
+// The configuration files are sourced the way bash would source them (see
+// Source), starting from the last file in Paths, so that the files listed
+// first take precedence over the following ones. The project-local file
+// named Project, found in the working directory or in its parents, takes
+// precedence over all of them.
+//
+// A profile is a named set of settings, defined either by a section of a
+// configuration file (starting with a line such as [profile work]) or by a
+// file named after the profile inside ProfileDirs (ex.: work.cfg). The
+// settings of the selected profile are sourced after all the configuration
+// files, so they take precedence over the settings outside any profile.
+//
+// Environment variables take precedence over configuration files: the
+// assignments to variables already set in the environment are ignored.
+type Loader struct {
+	Paths       []string                // configuration files; variables are expanded
+	Project     string                  // name of the project-local file; empty to disable
+	ProfileDirs []string                // directories of the profile files; variables are expanded
+	Profile     string                  // profile to load; defaults to $TODOTXT_PROFILE
+	Getenv      func(key string) string // lookup of the environment variables
+	Home        string                  // home directory; defaults to $HOME
+	Pwd         string                  // working directory; defaults to os.Getwd()
+}
+
+// NewLoader returns a Loader which reads the default configuration files and
+// the environment variables of the process.
+func NewLoader() *Loader {
+	return &Loader{
+		Paths:       append([]string{}, cfgPath...),
+		Project:     cfgProjectFile,
+		ProfileDirs: append([]string{}, cfgProfileDirs...),
+		Getenv:      os.Getenv,
+	}
+}
+
+// loaderVars holds the shell variables defined while loading a configuration.
+type loaderVars struct {
+	loader *Loader
+	cfg    *Config
+	vars   map[string]string
+	file   string // configuration file being sourced
+}
+
+// Lookup returns the value of a variable, looking at the environment first.
+func (v *loaderVars) Lookup(name string) (string, bool) {
+	switch name {
+	case "HOME":
+		return v.cfg.Home, v.cfg.Home != ""
+	case "PWD":
+		return v.cfg.Pwd, true
+	}
+	if value := v.loader.Getenv(name); value != "" {
+		return value, true
+	}
+	value, ok := v.vars[name]
+	return value, ok
+}
+
+// Set assigns a variable, unless it is already set in the environment.
+func (v *loaderVars) Set(name, value string) {
+	if name == "HOME" || name == "PWD" || v.loader.Getenv(name) != "" {
+		return
+	}
+	v.vars[name] = value
+	v.cfg.origins[name] = v.file
+}
+
+// Load reads all the configuration files (todo.cfg) and the environment
+// variables, and then creates a configuration filled with their settings.
+func (l *Loader) Load() (*Config, error) {
+	cfg := NewConfig()
+
+	// Retrieve environment variables $HOME and $PWD
+	cfg.Home, cfg.Pwd = l.Home, l.Pwd
+	if cfg.Home == "" && l.Getenv("HOME") != "" {
+		cfg.Home = path.Clean(l.Getenv("HOME"))
+	}
+	if cfg.Pwd == "" {
+		pwd, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+		cfg.Pwd = path.Clean(pwd)
+	}
+
+	vars := &loaderVars{loader: l, cfg: cfg, vars: map[string]string{}}
+	files, err := l.files(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	// Source all the configuration files, from the lowest precedence; the
+	// sections of the profiles are set aside until the profile is known
+	sections := map[string][]profileSource{}
+	for i := len(files) - 1; i >= 0; i-- {
+		content, err := ioutil.ReadFile(files[i])
+		if err != nil {
+			return nil, err
+		}
+		base, profiles := splitProfiles(string(content))
+
+		vars.file = files[i]
+		if err := Source(strings.NewReader(base), files[i], vars); err != nil {
+			return nil, err
+		}
+		for name, section := range profiles {
+			origin := fmt.Sprintf("%s [profile %s]", files[i], name)
+			sections[name] = append(sections[name], profileSource{origin, section})
+		}
+		cfg.Files = append([]string{files[i]}, cfg.Files...)
+	}
+
+	// Source the selected profile
+	profile, err := l.loadProfile(cfg, vars, sections)
+	if err != nil {
+		return nil, err
+	}
+
+	// Populate the configuration with the known settings only
+	for _, name := range cfg.Names() {
+		value, ok := vars.Lookup(name)
+		if !ok {
+			continue
+		}
+		if l.Getenv(name) != "" {
+			cfg.origins[name] = OriginEnvironment
+		}
+		if err := cfg.Set(name, value); err != nil {
+			return nil, WrapError(ErrConfig, err, fmt.Sprintf("Please fix %s in %s.", name, cfg.Origin(name)))
+		}
+	}
+	if l.Profile != "" {
+		cfg.origins["TODOTXT_PROFILE"] = OriginCommandLine
+	}
+	cfg.Profile = profile
+	return cfg, nil
+}
+
+// loadProfile sources the settings of the selected profile, given the
+// sections of the profiles found inside the configuration files. It also
+// lists all the profiles inside cfg, and returns the selected one.
+func (l *Loader) loadProfile(cfg *Config, vars *loaderVars, sections map[string][]profileSource) (string, error) {
+	profileFiles, err := l.profileFiles(vars)
+	if err != nil {
+		return "", err
+	}
+	for name := range sections {
+		cfg.Profiles = append(cfg.Profiles, name)
+	}
+	for name := range profileFiles {
+		if _, ok := sections[name]; !ok {
+			cfg.Profiles = append(cfg.Profiles, name)
+		}
+	}
+	sort.Strings(cfg.Profiles)
+
+	profile := l.Profile
+	if profile == "" {
+		profile, _ = vars.Lookup("TODOTXT_PROFILE")
+	}
+	if profile == "" {
+		return "", nil
+	}
+
+	file, ok := profileFiles[profile]
+	if !ok && len(sections[profile]) == 0 {
+		return "", NewError(ErrConfig, "The available profiles are listed by 'todo profile list'.",
+			"unknown profile %q", profile)
+	}
+
+	// the profile file comes first, then the sections from the lowest
+	// precedence configuration file
+	if ok {
+		vars.file = file
+		if err := sourceFile(file, vars); err != nil {
+			return "", err
+		}
+		cfg.ProfileFile = file
+	}
+	for _, section := range sections[profile] {
+		vars.file = section.origin
+		if err := Source(strings.NewReader(section.content), section.origin, vars); err != nil {
+			return "", err
+		}
+	}
+	return profile, nil
+}
+
+// files returns the existing configuration files, in order of precedence.
+func (l *Loader) files(vars Vars) ([]string, error) {
+	files := []string{}
+	seen := map[string]bool{}
+	add := func(file string) error {
+		ret, err := Exists(file)
+		if err != nil || !ret {
+			return err
+		}
+		if abs, err := filepath.Abs(file); err == nil && !seen[abs] {
+			seen[abs] = true
+			files = append(files, file)
+		}
+		return nil
+	}
+
+	// the project-local file comes first
+	if l.Project != "" {
+		project, err := Discover(l.Project, vars)
+		if err != nil {
+			return nil, err
+		}
+		if project != "" && !l.isDefault(project, vars) {
+			if err := add(project); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, p := range l.Paths {
+		// paths relative to an empty $HOME are skipped
+		if strings.Contains(p, "$HOME") {
+			if home, _ := vars.Lookup("HOME"); home == "" {
+				continue
+			}
+		}
+		file, err := Expand(p, vars)
+		if err != nil {
+			return nil, err
+		}
+		if err := add(file); err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// isDefault returns true if file is one of the files in Paths, which are
+// sourced with their own precedence.
+func (l *Loader) isDefault(file string, vars Vars) bool {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return false
+	}
+	for _, p := range l.Paths {
+		expanded, err := Expand(p, vars)
+		if err != nil {
+			continue
+		}
+		if other, err := filepath.Abs(expanded); err == nil && other == abs {
+			return true
+		}
+	}
+	return false
+}
+
+// Discover returns the nearest file named name inside the working directory
+// ($PWD) or any of its parents, or an empty string if there is none.
+func Discover(name string, vars Vars) (string, error) {
+	dir, _ := vars.Lookup("PWD")
+	if dir == "" {
+		return "", nil
+	}
+	for {
+		p := path.Join(dir, name)
+		ret, err := Exists(p)
+		if err != nil {
+			return "", err
+		}
+		if ret {
+			return p, nil
+		}
+		parent := path.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// sourceFile sources a configuration file, if it exists.
+func sourceFile(name string, vars Vars) error {
+	file, err := os.Open(name)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return Source(file, name, vars)
+}
@@ -0,0 +1,65 @@
+// Copyright (c) 2014, Mauro Toffanin. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package utils
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoaderProfiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "todo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cfgFile := filepath.Join(dir, "todo.cfg")
+	profileDir := filepath.Join(dir, "profiles")
+	write := func(file, content string) {
+		if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(file, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(cfgFile, "export TODO_DIR=/base\n[profile home]\nexport TODO_DIR=/home\n")
+	write(filepath.Join(profileDir, "work.cfg"), "export TODO_DIR=/work\n")
+
+	tests := []struct {
+		profile     string
+		todoDir     string
+		origin      string
+		profileFile string
+	}{
+		{"", "/base", cfgFile, ""},
+		{"home", "/home", cfgFile + " [profile home]", ""},
+		{"work", "/work", filepath.Join(profileDir, "work.cfg"), filepath.Join(profileDir, "work.cfg")},
+	}
+	for _, test := range tests {
+		l := &Loader{Paths: []string{cfgFile}, ProfileDirs: []string{profileDir}, Profile: test.profile,
+			Getenv: func(string) string { return "" }, Home: dir, Pwd: dir}
+		cfg, err := l.Load()
+		if err != nil {
+			t.Errorf("profile %q: %v", test.profile, err)
+			continue
+		}
+		if cfg.TodoDir != test.todoDir || cfg.Origin("TODO_DIR") != test.origin {
+			t.Errorf("profile %q: TODO_DIR = %s from %s, want %s from %s", test.profile, cfg.TodoDir,
+				cfg.Origin("TODO_DIR"), test.todoDir, test.origin)
+		}
+		if !reflect.DeepEqual(cfg.Files, []string{cfgFile}) || cfg.ProfileFile != test.profileFile {
+			t.Errorf("profile %q: files %q and profile file %q, want %q and %q", test.profile, cfg.Files,
+				cfg.ProfileFile, []string{cfgFile}, test.profileFile)
+		}
+		if !reflect.DeepEqual(cfg.Profiles, []string{"home", "work"}) {
+			t.Errorf("profile %q: profiles %q", test.profile, cfg.Profiles)
+		}
+	}
+}
@@ -6,14 +6,108 @@ package utils
 
 import (
 	"fmt"
+	"io"
+	"os"
+	"runtime/debug"
 )
 
-// (WIP) This function is an ugly and hackish error handler that needs to be
-// replaced with something more complete and useful. Don't use it.
-func Check(e error) {
-	// TODO: replace Check() with github.com/juju/errgo helpers.
-	if e != nil {
-		fmt.Errorf("%v", e)
-		panic(e)
+// Exit codes of the todo command, by kind of error.
+const (
+	ExitOK      = 0 // Success
+	ExitFailure = 1 // The command failed (ex.: a task doesn't exist)
+	ExitUsage   = 2 // Incorrect usage of a command
+	ExitConfig  = 3 // Invalid configuration
+	ExitIO      = 4 // A file can't be read or written
+	ExitParse   = 5 // Malformed input
+)
+
+// An ErrorKind classifies the errors of the todo command.
+type ErrorKind int
+
+// Kinds of errors.
+const (
+	ErrFailure ErrorKind = iota // generic failure
+	ErrUsage                    // incorrect usage of a command
+	ErrConfig                   // invalid configuration
+	ErrIO                       // a file can't be read or written
+	ErrParse                    // malformed input
+)
+
+// An Error is an error reported to the user of the todo command, along with
+// a hint to fix it.
+type Error struct {
+	Kind  ErrorKind
+	Err   error  // Underlying error
+	Hint  string // How to fix the error; empty if unknown
+	Stack []byte // Stack trace of the goroutine which created the error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// ExitCode returns the exit code matching the kind of the error.
+func (e *Error) ExitCode() int {
+	switch e.Kind {
+	case ErrUsage:
+		return ExitUsage
+	case ErrConfig:
+		return ExitConfig
+	case ErrIO:
+		return ExitIO
+	case ErrParse:
+		return ExitParse
+	}
+	return ExitFailure
+}
+
+// NewError returns an Error of the given kind with a formatted message.
+func NewError(kind ErrorKind, hint string, format string, args ...interface{}) error {
+	return &Error{Kind: kind, Err: fmt.Errorf(format, args...), Hint: hint, Stack: debug.Stack()}
+}
+
+// WrapError returns err as an Error of the given kind, or nil if err is nil.
+// Errors which are already an Error are returned unchanged.
+func WrapError(kind ErrorKind, err error, hint string) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(*Error); ok {
+		return err
+	}
+	return &Error{Kind: kind, Err: err, Hint: hint, Stack: debug.Stack()}
+}
+
+// ExitCode returns the exit code matching err: the exit code of its kind for
+// an Error, ExitConfig for a SyntaxError of a configuration file, ExitIO for
+// the errors of the filesystem, and ExitFailure otherwise.
+func ExitCode(err error) int {
+	switch e := err.(type) {
+	case nil:
+		return ExitOK
+	case *Error:
+		return e.ExitCode()
+	case *SyntaxError:
+		return ExitConfig
+	case *os.PathError, *os.LinkError, *os.SyscallError:
+		return ExitIO
+	}
+	return ExitFailure
+}
+
+// PrintError prints err to w as a message for the user, along with its hint.
+// The stack trace of the error is printed too when verbose is at least 2.
+func PrintError(w io.Writer, err error, verbose int) {
+	fmt.Fprintf(w, "TODO: %s\n", err)
+
+	e, ok := err.(*Error)
+	if !ok {
+		return
+	}
+	if e.Hint != "" {
+		fmt.Fprintln(w, e.Hint)
+	}
+	if verbose > 1 && e.Stack != nil {
+		fmt.Fprintf(w, "\n%s", e.Stack)
 	}
 }
@@ -0,0 +1,454 @@
+// Copyright (c) 2014, Mauro Toffanin. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os/user"
+)
+
+// Vars is the set of shell variables visible while a todo.cfg file is
+// sourced.
+type Vars interface {
+	// Lookup returns the value of the variable name, and false if the
+	// variable isn't set.
+	Lookup(name string) (string, bool)
+
+	// Set assigns a value to the variable name.
+	Set(name, value string)
+}
+
+// A SyntaxError is returned when a todo.cfg file can't be sourced.
+type SyntaxError struct {
+	File string // Name of the file
+	Line int    // Line where the error occurred
+	Msg  string // Description of the error
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Msg)
+}
+
+// Source evaluates the todo.cfg file read from r the way bash would source
+// it, assigning the variables to vars in file order. The name of the file is
+// used to report errors.
+//
+// The following subset of the bash syntax is supported:
+//
+//	# comments, also at the end of a line
+//	NAME=value NAME2=value     assignments, optionally prefixed by 'export'
+//	'single quoted'            literal strings
+//	"double quoted"            strings with expansions and \ escapes
+//	\x                         escaped characters and line continuations
+//	~ ~/dir ~user              home directories (also after ':')
+//	$NAME ${NAME}              variables
+//	${NAME:-word} ${NAME-word} default values
+//	${NAME:=word} ${NAME=word} default values, which are also assigned
+//	${NAME:+word} ${NAME+word} alternative values
+//	: ${NAME:=word}            the null command, to assign default values
+//
+// Command substitutions ($(...) and `...`) aren't supported.
+func Source(r io.Reader, name string, vars Vars) error {
+	input, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	s := &sourcer{input: []rune(string(input)), line: 1, file: name, vars: vars}
+	return s.source()
+}
+
+// Expand evaluates a single word (ex.: "$HOME/todo.cfg") with vars, as bash
+// would evaluate the right side of an assignment.
+func Expand(word string, vars Vars) (string, error) {
+	s := &sourcer{input: []rune(word), line: 1, file: word, vars: vars}
+	value, err := s.word(0)
+	if err == nil && !s.eof() {
+		err = s.errorf("unexpected %q", s.peek())
+	}
+	return value, err
+}
+
+// sourcer holds the state of the evaluation of a todo.cfg file.
+type sourcer struct {
+	input []rune
+	pos   int
+	line  int
+	file  string
+	vars  Vars
+	dry   int // when > 0, words are parsed without side effects
+}
+
+func (s *sourcer) eof() bool {
+	return s.pos >= len(s.input)
+}
+
+func (s *sourcer) peek() rune {
+	if s.eof() {
+		return 0
+	}
+	return s.input[s.pos]
+}
+
+func (s *sourcer) next() rune {
+	r := s.peek()
+	s.pos++
+	if r == '\n' {
+		s.line++
+	}
+	return r
+}
+
+func (s *sourcer) errorf(format string, args ...interface{}) error {
+	return &SyntaxError{File: s.file, Line: s.line, Msg: fmt.Sprintf(format, args...)}
+}
+
+// skipBlanks skips spaces and tabs.
+func (s *sourcer) skipBlanks() {
+	for r := s.peek(); r == ' ' || r == '\t'; r = s.peek() {
+		s.next()
+	}
+}
+
+// skipComment skips a comment until the end of the line.
+func (s *sourcer) skipComment() {
+	for !s.eof() && s.peek() != '\n' {
+		s.next()
+	}
+}
+
+// endOfLine returns true at a line ending, "\n" or "\r\n" (files edited on
+// Windows).
+func (s *sourcer) endOfLine() bool {
+	switch s.peek() {
+	case '\n':
+		return true
+	case '\r':
+		return s.pos+1 < len(s.input) && s.input[s.pos+1] == '\n'
+	}
+	return false
+}
+
+// skipLineEnding skips the line ending at the current position.
+func (s *sourcer) skipLineEnding() {
+	if s.peek() == '\r' {
+		s.next()
+	}
+	s.next()
+}
+
+// endOfStatement returns true if the current statement is over.
+func (s *sourcer) endOfStatement() bool {
+	r := s.peek()
+	return s.eof() || s.endOfLine() || r == ';' || r == '#'
+}
+
+// source evaluates all the statements of the input.
+func (s *sourcer) source() error {
+	for {
+		// skip blank lines, separators and comments
+		for r := s.peek(); r == ' ' || r == '\t' || r == '\n' || r == ';' || r == '\r'; r = s.peek() {
+			s.next()
+		}
+		if s.eof() {
+			return nil
+		}
+		if s.peek() == '#' {
+			s.skipComment()
+			continue
+		}
+		if err := s.statement(); err != nil {
+			return err
+		}
+	}
+}
+
+// statement evaluates a list of assignments, optionally prefixed by 'export'.
+func (s *sourcer) statement() error {
+	// the null command ':' only evaluates its arguments (ex.: : ${NAME:=word})
+	if s.peek() == ':' {
+		s.next()
+		for s.skipBlanks(); !s.endOfStatement(); s.skipBlanks() {
+			if _, err := s.word(0); err != nil {
+				return err
+			}
+		}
+		return s.endStatement()
+	}
+
+	export := false
+	for {
+		start := s.pos
+		name := s.name()
+		if name == "" {
+			return s.errorf("expected a variable assignment, found %q", s.peek())
+		}
+
+		if s.peek() != '=' {
+			// 'export' followed by names or assignments
+			if !export && name == "export" && (s.peek() == ' ' || s.peek() == '\t') {
+				export = true
+				s.skipBlanks()
+				if s.endOfStatement() {
+					break
+				}
+				continue
+			}
+			if export && (s.peek() == ' ' || s.peek() == '\t' || s.endOfStatement()) {
+				// 'export NAME' doesn't change the value of NAME
+				s.skipBlanks()
+				if s.endOfStatement() {
+					break
+				}
+				continue
+			}
+			s.pos = start
+			return s.errorf("commands are not supported, expected a variable assignment")
+		}
+		s.next() // '='
+
+		value, err := s.word(0)
+		if err != nil {
+			return err
+		}
+		s.vars.Set(name, value)
+
+		s.skipBlanks()
+		if s.endOfStatement() {
+			break
+		}
+	}
+
+	return s.endStatement()
+}
+
+// endStatement skips the trailing comment of a statement.
+func (s *sourcer) endStatement() error {
+	if s.peek() == '#' {
+		s.skipComment()
+	}
+	return nil
+}
+
+// name reads a variable name.
+func (s *sourcer) name() string {
+	start := s.pos
+	for !s.eof() {
+		r := s.peek()
+		if r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') ||
+			(r >= '0' && r <= '9' && s.pos > start) {
+			s.pos++
+			continue
+		}
+		break
+	}
+	return string(s.input[start:s.pos])
+}
+
+// word reads and evaluates a word. A word ends at the first unquoted blank or
+// statement separator, or at the first unquoted closing rune if closing isn't
+// 0 (in such case blanks are part of the word).
+func (s *sourcer) word(closing rune) (string, error) {
+	var b bytes.Buffer
+	start := true // at the start of the word or after an unquoted ':'
+
+	for !s.eof() {
+		r := s.peek()
+		if closing != 0 && r == closing {
+			break
+		}
+		if closing == 0 && (r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == ';') {
+			break
+		}
+
+		switch r {
+		case '\\':
+			s.next()
+			if s.eof() {
+				b.WriteRune('\\')
+				break
+			}
+			// a line continuation is removed
+			if s.endOfLine() {
+				s.skipLineEnding()
+				break
+			}
+			b.WriteRune(s.next())
+		case '\'':
+			s.next()
+			for !s.eof() && s.peek() != '\'' {
+				b.WriteRune(s.next())
+			}
+			if s.eof() {
+				return "", s.errorf("unterminated single-quoted string")
+			}
+			s.next()
+		case '"':
+			value, err := s.doubleQuoted()
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(value)
+		case '$':
+			value, err := s.expansion()
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(value)
+		case '`':
+			return "", s.errorf("command substitution is not supported")
+		case '~':
+			if start {
+				b.WriteString(s.tilde())
+				start = false
+				continue
+			}
+			b.WriteRune(s.next())
+		default:
+			b.WriteRune(s.next())
+		}
+		start = r == ':'
+	}
+	return b.String(), nil
+}
+
+// doubleQuoted reads and evaluates a double-quoted string.
+func (s *sourcer) doubleQuoted() (string, error) {
+	var b bytes.Buffer
+	s.next() // '"'
+	for {
+		if s.eof() {
+			return "", s.errorf("unterminated double-quoted string")
+		}
+		switch r := s.peek(); r {
+		case '"':
+			s.next()
+			return b.String(), nil
+		case '\\':
+			s.next()
+			if s.endOfLine() {
+				// a line continuation is removed
+				s.skipLineEnding()
+				break
+			}
+			switch escaped := s.peek(); escaped {
+			case '$', '`', '"', '\\':
+				b.WriteRune(s.next())
+			default:
+				b.WriteRune('\\')
+			}
+		case '$':
+			value, err := s.expansion()
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(value)
+		case '`':
+			return "", s.errorf("command substitution is not supported")
+		default:
+			b.WriteRune(s.next())
+		}
+	}
+}
+
+// expansion reads and evaluates a parameter expansion starting with '$'.
+func (s *sourcer) expansion() (string, error) {
+	s.next() // '$'
+	switch r := s.peek(); {
+	case r == '{':
+		s.next()
+		return s.braced()
+	case r == '(':
+		return "", s.errorf("command substitution is not supported")
+	case r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z'):
+		value, _ := s.vars.Lookup(s.name())
+		return value, nil
+	}
+	// a lone '$' is literal
+	return "$", nil
+}
+
+// braced evaluates the parameter expansion ${...} after the opening brace.
+func (s *sourcer) braced() (string, error) {
+	name := s.name()
+	if name == "" {
+		return "", s.errorf("bad substitution")
+	}
+	value, set := s.vars.Lookup(name)
+
+	if s.peek() == '}' {
+		s.next()
+		return value, nil
+	}
+
+	// operators with a leading ':' test for empty values too
+	colon := false
+	if s.peek() == ':' {
+		colon = true
+		s.next()
+	}
+	op := s.next()
+	if op != '-' && op != '=' && op != '+' {
+		return "", s.errorf("bad substitution")
+	}
+	missing := !set || (colon && value == "")
+
+	// the word is evaluated only when it is used
+	use := missing
+	if op == '+' {
+		use = !missing
+	}
+	if !use {
+		s.dry++
+	}
+	word, err := s.word('}')
+	if !use {
+		s.dry--
+	}
+	if err != nil {
+		return "", err
+	}
+	if s.eof() {
+		return "", s.errorf("unterminated parameter expansion")
+	}
+	s.next() // '}'
+
+	switch {
+	case op == '+' && missing:
+		return "", nil
+	case op == '+':
+		return word, nil
+	case !missing:
+		return value, nil
+	case op == '=' && s.dry == 0:
+		s.vars.Set(name, word)
+	}
+	return word, nil
+}
+
+// tilde evaluates a tilde prefix (~ or ~user) at the start of a word.
+func (s *sourcer) tilde() string {
+	start := s.pos
+	s.next() // '~'
+	login := s.name()
+	if r := s.peek(); !(s.eof() || r == '/' || r == ':' || r == ' ' || r == '\t' ||
+		r == '\n' || r == ';' || r == '}') {
+		// not a tilde prefix (ex.: ~foo-bar)
+		s.pos = start + 1
+		return "~"
+	}
+
+	if login == "" {
+		home, _ := s.vars.Lookup("HOME")
+		return home
+	}
+	if u, err := user.Lookup(login); err == nil {
+		return u.HomeDir
+	}
+	s.pos = start + 1
+	return "~"
+}
@@ -0,0 +1,137 @@
+// Copyright (c) 2014, Mauro Toffanin. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package utils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// mapVars is a set of variables held by a map.
+type mapVars map[string]string
+
+func (v mapVars) Lookup(name string) (string, bool) {
+	value, ok := v[name]
+	return value, ok
+}
+
+func (v mapVars) Set(name, value string) {
+	v[name] = value
+}
+
+func TestExpand(t *testing.T) {
+	tests := []struct {
+		word string
+		want string
+		vars mapVars // variables after the expansion, if they change
+	}{
+		{`plain`, "plain", nil},
+		{`$HOME/todo.cfg`, "/home/mary/todo.cfg", nil},
+		{`${HOME}/todo.cfg`, "/home/mary/todo.cfg", nil},
+		{`~/todo`, "/home/mary/todo", nil},
+		{`/bin:~/bin`, "/bin:/home/mary/bin", nil},
+		{`a~b`, "a~b", nil},
+		{`'$HOME'`, "$HOME", nil},
+		{`"$HOME dir"`, "/home/mary dir", nil},
+		{`"\$HOME"`, "$HOME", nil},
+		{`a\ b`, "a b", nil},
+		{`$MISSING`, "", nil},
+		{`cost$`, "cost$", nil},
+		{`${MISSING:-default}`, "default", nil},
+		{`${EMPTY:-default}`, "default", nil},
+		{`${EMPTY-default}`, "", nil},
+		{`${HOME:-default}`, "/home/mary", nil},
+		{`${HOME:+set}`, "set", nil},
+		{`${MISSING:+set}`, "", nil},
+		{`${EMPTY+set}`, "set", nil},
+		{`${MISSING:=$HOME/todo}`, "/home/mary/todo",
+			mapVars{"HOME": "/home/mary", "EMPTY": "", "MISSING": "/home/mary/todo"}},
+		{`${HOME:-${MISSING:=x}}`, "/home/mary", nil},
+	}
+	for _, test := range tests {
+		vars := mapVars{"HOME": "/home/mary", "EMPTY": ""}
+		got, err := Expand(test.word, vars)
+		if err != nil {
+			t.Errorf("Expand(%s): %v", test.word, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("Expand(%s) = %q, want %q", test.word, got, test.want)
+		}
+		want := test.vars
+		if want == nil {
+			want = mapVars{"HOME": "/home/mary", "EMPTY": ""}
+		}
+		if !reflect.DeepEqual(vars, want) {
+			t.Errorf("Expand(%s) left variables %v, want %v", test.word, vars, want)
+		}
+	}
+}
+
+func TestExpandErrors(t *testing.T) {
+	for _, word := range []string{`'open`, `"open`, `${HOME`, `${}`, `${HOME?x}`, "`date`", `$(date)`} {
+		if got, err := Expand(word, mapVars{"HOME": "/home/mary"}); err == nil {
+			t.Errorf("Expand(%s) = %q, want an error", word, got)
+		}
+	}
+}
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		input string
+		want  mapVars
+	}{
+		{"", mapVars{}},
+		{"# comment\n\nA=1\n", mapVars{"A": "1"}},
+		{"export A=1 B=2", mapVars{"A": "1", "B": "2"}},
+		{"A=1; B=$A$A # comment", mapVars{"A": "1", "B": "11"}},
+		{"export A\nA=1", mapVars{"A": "1"}},
+		{"A='x y' B=\"$A z\"", mapVars{"A": "x y", "B": "x y z"}},
+		{"A=long\\\nline", mapVars{"A": "longline"}},
+		{"# comment\r\nexport A=1\r\nB=\"$A 2\" # two\r\nC=x\\\r\ny D=\"u\\\r\nv\"\r\n",
+			mapVars{"A": "1", "B": "1 2", "C": "xy", "D": "uv"}},
+		{"export A\r\n: ${A:=1}\r\n", mapVars{"A": "1"}},
+		{": ${A:=1}\n: ${A:=2}", mapVars{"A": "1"}},
+		{"export TODO_DIR=$HOME/todo\nexport TODO_FILE=\"$TODO_DIR/todo.txt\"",
+			mapVars{"TODO_DIR": "/home/mary/todo", "TODO_FILE": "/home/mary/todo/todo.txt"}},
+	}
+	for _, test := range tests {
+		vars := mapVars{"HOME": "/home/mary"}
+		if err := Source(strings.NewReader(test.input), "todo.cfg", vars); err != nil {
+			t.Errorf("Source(%q): %v", test.input, err)
+			continue
+		}
+		delete(vars, "HOME")
+		if !reflect.DeepEqual(vars, test.want) {
+			t.Errorf("Source(%q) = %v, want %v", test.input, vars, test.want)
+		}
+	}
+}
+
+func TestSourceErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		line  int
+	}{
+		{"A=1\necho hello", 2},
+		{"A=1\r\necho hello\r\n", 2},
+		{"A=1\nB='open", 2},
+		{"A=$(date)", 1},
+		{"\n\n=1", 3},
+	}
+	for _, test := range tests {
+		err := Source(strings.NewReader(test.input), "todo.cfg", mapVars{})
+		serr, ok := err.(*SyntaxError)
+		if !ok {
+			t.Errorf("Source(%q) = %v, want a SyntaxError", test.input, err)
+			continue
+		}
+		if serr.File != "todo.cfg" || serr.Line != test.line {
+			t.Errorf("Source(%q) failed at %s:%d, want todo.cfg:%d", test.input, serr.File, serr.Line,
+				test.line)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2014, Mauro Toffanin. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// IO holds the streams used by an application to interact with the user.
+//
+// Applications embedding the commands, and tests, can replace the standard
+// streams with their own readers and writers:
+//
+//	var out bytes.Buffer
+//	stdio := utils.NewIO(strings.NewReader("Buy milk\n"), &out, &out)
+//	stdio.IsTerminal = true // answer the prompts from the reader
+type IO struct {
+	In  io.Reader // Input of the prompts
+	Out io.Writer // Output of the commands
+	Err io.Writer // Errors and diagnostics
+
+	// IsTerminal is true if In is an interactive terminal. Prompts fail
+	// instead of waiting for an input which will never come otherwise.
+	IsTerminal bool
+
+	reader *bufio.Reader // buffered In, shared by all the prompts
+}
+
+// NewIO returns an IO using the given streams. The input isn't considered an
+// interactive terminal.
+func NewIO(in io.Reader, out, err io.Writer) *IO {
+	return &IO{In: in, Out: out, Err: err}
+}
+
+// StdIO returns an IO using the standard streams of the process. The input
+// is an interactive terminal unless it is redirected from a file or a pipe.
+func StdIO() *IO {
+	stdio := NewIO(os.Stdin, os.Stdout, os.Stderr)
+	if info, err := os.Stdin.Stat(); err == nil {
+		stdio.IsTerminal = info.Mode()&os.ModeCharDevice != 0
+	}
+	return stdio
+}
+
+// Prompt shows a prompt on Out and then reads a line from In, sanitized with
+// SanitizeInput. It returns an error if In isn't an interactive terminal, or
+// if nothing can be read.
+func (s *IO) Prompt(prompt string) (string, error) {
+	if !s.IsTerminal {
+		return "", NewError(ErrUsage, "Please provide the input as arguments of the command.",
+			"Can't prompt %q: the input is not a terminal.", prompt)
+	}
+	if prompt != "" {
+		fmt.Fprintf(s.Out, "%s ", prompt)
+	}
+
+	line, err := s.ReadLine()
+	if err == io.EOF {
+		return "", NewError(ErrFailure, "", "Can't prompt %q: end of input.", prompt)
+	}
+	if err != nil {
+		return "", err
+	}
+	return SanitizeInput(line), nil
+}
+
+// ReadLine reads a line from In, without the end-of-line marker. It returns
+// io.EOF when there is nothing left to read.
+func (s *IO) ReadLine() (string, error) {
+	if s.reader == nil {
+		s.reader = bufio.NewReader(s.In)
+	}
+	line, err := s.reader.ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+	if err != nil && err != io.EOF {
+		return "", WrapError(ErrIO, err, "")
+	}
+	return strings.TrimRight(line, "\r\n"), err
+}
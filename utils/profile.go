@@ -0,0 +1,99 @@
+// Copyright (c) 2014, Mauro Toffanin. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package utils
+
+import (
+	"io/ioutil"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// profileHeader matches the line starting the section of a profile inside a
+// configuration file (ex.: [profile work]).
+var profileHeader = regexp.MustCompile(`^[ \t]*\[profile[ \t]+([A-Za-z0-9_.-]+)\][ \t]*(#.*)?$`)
+
+// profileSource is a part of a configuration file defining a profile.
+type profileSource struct {
+	origin  string // file (and section) defining the profile
+	content string
+}
+
+// splitProfiles splits the content of a configuration file into the settings
+// outside any profile and the sections of each profile. Every part keeps the
+// lines of the file in place, blanking the lines of the other parts, so that
+// errors are reported at the right line.
+func splitProfiles(content string) (string, map[string]string) {
+	lines := strings.Split(content, "\n")
+	base := make([]string, len(lines))
+	sections := map[string][]string{}
+
+	current := ""
+	for i, line := range lines {
+		if match := profileHeader.FindStringSubmatch(line); match != nil {
+			current = match[1]
+			if sections[current] == nil {
+				sections[current] = make([]string, len(lines))
+			}
+			continue
+		}
+		if current == "" {
+			base[i] = line
+		} else {
+			sections[current][i] = line
+		}
+	}
+
+	profiles := map[string]string{}
+	for name, section := range sections {
+		profiles[name] = strings.Join(section, "\n")
+	}
+	return strings.Join(base, "\n"), profiles
+}
+
+// firstProfile returns the offset of the first profile section of the content
+// of a configuration file, or the length of the content if there is none.
+func firstProfile(content string) int {
+	offset := 0
+	for _, line := range strings.SplitAfter(content, "\n") {
+		if profileHeader.MatchString(strings.TrimRight(line, "\r\n")) {
+			return offset
+		}
+		offset += len(line)
+	}
+	return len(content)
+}
+
+// profileFiles returns the profile files (NAME.cfg) found inside ProfileDirs,
+// by profile name.
+func (l *Loader) profileFiles(vars Vars) (map[string]string, error) {
+	files := map[string]string{}
+	for _, p := range l.ProfileDirs {
+		// paths relative to an empty $HOME are skipped
+		if strings.Contains(p, "$HOME") {
+			if home, _ := vars.Lookup("HOME"); home == "" {
+				continue
+			}
+		}
+		dir, err := Expand(p, vars)
+		if err != nil {
+			return nil, err
+		}
+		entries, err := ioutil.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			name := strings.TrimSuffix(entry.Name(), ".cfg")
+			if entry.IsDir() || name == entry.Name() {
+				continue
+			}
+			if _, ok := files[name]; !ok {
+				files[name] = path.Join(dir, entry.Name())
+			}
+		}
+	}
+	return files, nil
+}
@@ -16,7 +16,9 @@
 //
 //   func main() {
 //     // Load Todo.txt CLI settings from todo.cfg
-//     utils.LoadConfig()
+//     if err := utils.LoadConfig(); err != nil {
+//        log.Fatal(err)
+//     }
 //
 //     settings := utils.GetSettings()
 //     fmt.Printf("Settings: %#v\n", settings)
@@ -33,12 +35,21 @@
 //     // do something with todoDir and todoActionsDir
 //  }
 //
+// Applications which need more than one configuration, or which want to
+// customize the configuration files, can build a typed Config with a Loader:
+//
+//  func main() {
+//     cfg, err := utils.NewLoader().Load()
+//     if err != nil {
+//        log.Fatal(err)
+//     }
+//
+//     // do something with cfg.TodoDir and cfg.ActionsDir
+//  }
+//
 package utils
 
 import (
-	"bufio"
-	"fmt"
-	"os"
 	"strings"
 )
 
@@ -65,16 +76,9 @@ func GetPwd() string {
 }
 
 // InteractiveInput shows a prompt and then reads a String provided by a user at
-// a command-line.
-func InteractiveInput(prompt string) string {
-	if prompt != "" {
-		fmt.Printf("%s ", prompt)
-	}
-	input, err := bufio.NewReader(os.Stdin).ReadString('\n')
-	Check(err)
-
-	// sanitize input
-	return SanitizeInput(input)
+// a command-line, using the standard streams (see IO.Prompt).
+func InteractiveInput(prompt string) (string, error) {
+	return StdIO().Prompt(prompt)
 }
 
 // SanitizeInput applies the following rules iteratively until no further